@@ -0,0 +1,115 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// ListAs calls List with the configuration of client, decoding the resources into T.
+// If the filter has no projection, it is set to the json fields of T.
+// The client must be created by NewClient.
+func ListAs[T any, Resource any](ctx context.Context, client CrudClient[Resource], options Options) ([]T, error) {
+	projectionClient, err := clientAs[T](client)
+	if err != nil {
+		return nil, err
+	}
+	return projectionClient.List(ctx, optionsAs[T](options))
+}
+
+// GetByIDAs calls GetByID with the configuration of client, decoding the resource into T.
+// If the filter has no projection, it is set to the json fields of T.
+// The client must be created by NewClient.
+func GetByIDAs[T any, Resource any](ctx context.Context, client CrudClient[Resource], id string, options Options) (*T, error) {
+	projectionClient, err := clientAs[T](client)
+	if err != nil {
+		return nil, err
+	}
+	return projectionClient.GetByID(ctx, id, optionsAs[T](options))
+}
+
+// ExportAs calls Export with the configuration of client, decoding the resources into T.
+// If the filter has no projection, it is set to the json fields of T.
+// The client must be created by NewClient.
+func ExportAs[T any, Resource any](ctx context.Context, client CrudClient[Resource], options Options) ([]T, error) {
+	projectionClient, err := clientAs[T](client)
+	if err != nil {
+		return nil, err
+	}
+	return projectionClient.Export(ctx, optionsAs[T](options))
+}
+
+// clientAs returns a client of T with the configuration of client. The requests in
+// flight are not shared with client, since their results have another type, while
+// the cache is shared since its keys contain the result type, and the writes of
+// client invalidate the entries of both.
+func clientAs[T any, Resource any](client CrudClient[Resource]) (Client[T], error) {
+	c, ok := client.(Client[Resource])
+	if !ok {
+		return Client[T]{}, fmt.Errorf("%w: %T can not decode into another type", ErrCreateClient, client)
+	}
+	return Client[T]{
+		client:      c.client,
+		httpClient:  c.httpClient,
+		queryPolicy: c.queryPolicy,
+		cache:       c.cache,
+	}, nil
+}
+
+func optionsAs[T any](options Options) Options {
+	if len(options.Filter.Projection) == 0 {
+		options.Filter.Projection = projectionOf(reflect.TypeOf((*T)(nil)).Elem())
+	}
+	return options
+}
+
+// projectionOf returns the top level json fields of a struct type, or nil for
+// the other types and the structs without fields.
+func projectionOf(t reflect.Type) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return nil
+	}
+
+	var projection []string
+	add := func(name string) {
+		if !contains(projection, name) {
+			projection = append(projection, name)
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, ok := jsonFieldName(field)
+		if !ok {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			for _, embedded := range projectionOf(field.Type) {
+				add(embedded)
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		add(name)
+	}
+	return projection
+}
@@ -0,0 +1,169 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	gock "github.com/mia-platform/go-crud-service-client/testhelper/gock"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fieldOnly struct {
+	ID    string `json:"_id"`
+	Field string `json:"field"`
+}
+
+func TestListAs(t *testing.T) {
+	ctx := context.Background()
+	var client CrudClient[TestResource] = getClient(t)
+
+	t.Run("list into another type", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			AddMatcher(gock.CrudQueryMatcher(t, gock.Filter{
+				Projection: []string{"_id", "field"},
+				Limit:      2,
+			})).
+			MatchHeader("foo", "bar").
+			Reply(200).
+			JSON([]fieldOnly{{ID: "1", Field: "v-1"}, {ID: "2", Field: "v-2"}})
+
+		h := http.Header{}
+		h.Set("foo", "bar")
+
+		resources, err := ListAs[fieldOnly](ctx, client, Options{Filter: Filter{Limit: 2}, Headers: h})
+		require.NoError(t, err)
+		require.Equal(t, []fieldOnly{{ID: "1", Field: "v-1"}, {ID: "2", Field: "v-2"}}, resources)
+	})
+
+	t.Run("keep the projection of the filter", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			AddMatcher(gock.CrudQueryMatcher(t, gock.Filter{Projection: []string{"field"}})).
+			Reply(200).
+			JSON([]fieldOnly{{Field: "v-1"}})
+
+		resources, err := ListAs[fieldOnly](ctx, client, Options{Filter: Filter{Projection: []string{"field"}}})
+		require.NoError(t, err)
+		require.Equal(t, []fieldOnly{{Field: "v-1"}}, resources)
+	})
+
+	t.Run("use the client configuration", func(t *testing.T) {
+		crudClient, err := NewClient[TestResource](ClientOptions{
+			BaseURL:     baseURL,
+			Headers:     http.Header{"Client-Header": []string{"value"}},
+			QueryPolicy: &QueryPolicy{RequireListLimit: true},
+		})
+		require.NoError(t, err)
+
+		_, err = ListAs[fieldOnly](ctx, crudClient, Options{})
+		require.ErrorIs(t, err, ErrQueryPolicy)
+
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			MatchHeader("Client-Header", "value").
+			Reply(200).
+			JSON([]fieldOnly{})
+
+		_, err = ListAs[fieldOnly](ctx, crudClient, Options{Filter: Filter{Limit: 1}})
+		require.NoError(t, err)
+	})
+
+	t.Run("throws with other clients", func(t *testing.T) {
+		_, err := ListAs[fieldOnly](ctx, CrudClient[TestResource](&getByIDsClient{}), Options{})
+		require.ErrorIs(t, err, ErrCreateClient)
+	})
+
+	t.Run("do not share cache entries with the client", func(t *testing.T) {
+		type idOnly struct {
+			ID string `json:"_id"`
+		}
+		var cachedClient CrudClient[TestResource] = getCachedClient(t, CacheOptions{ListTTL: time.Minute})
+		options := Options{Filter: Filter{Projection: []string{"_id", "field"}}}
+
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			Times(2).
+			AddMatcher(gock.CrudQueryMatcher(t, gock.Filter{Projection: []string{"_id", "field"}})).
+			Reply(200).
+			JSON([]fieldOnly{{ID: "1", Field: "v-1"}})
+
+		ids, err := ListAs[idOnly](ctx, cachedClient, options)
+		require.NoError(t, err)
+		require.Equal(t, []idOnly{{ID: "1"}}, ids)
+
+		for i := 0; i < 2; i++ {
+			resources, err := cachedClient.List(ctx, options)
+			require.NoError(t, err)
+			require.Equal(t, []TestResource{{ID: "1", Field: "v-1"}}, resources)
+		}
+
+		ids, err = ListAs[idOnly](ctx, cachedClient, options)
+		require.NoError(t, err)
+		require.Equal(t, []idOnly{{ID: "1"}}, ids)
+	})
+}
+
+func TestGetByIDAs(t *testing.T) {
+	ctx := context.Background()
+	var client CrudClient[TestResource] = getClient(t)
+
+	gock.NewGockScope(t, baseURL, http.MethodGet, "my-id").
+		AddMatcher(gock.CrudQueryMatcher(t, gock.Filter{Projection: []string{"_id", "field"}})).
+		Reply(200).
+		JSON(fieldOnly{ID: "my-id", Field: "v"})
+
+	resource, err := GetByIDAs[fieldOnly](ctx, client, "my-id", Options{})
+	require.NoError(t, err)
+	require.Equal(t, &fieldOnly{ID: "my-id", Field: "v"}, resource)
+}
+
+func TestExportAs(t *testing.T) {
+	ctx := context.Background()
+	var client CrudClient[TestResource] = getClient(t)
+
+	gock.NewGockScope(t, baseURL, http.MethodGet, "export").
+		AddMatcher(gock.CrudQueryMatcher(t, gock.Filter{Projection: []string{"_id", "field"}})).
+		Reply(200).
+		BodyString(`{"_id":"1","field":"v-1"}` + "\n" + `{"_id":"2","field":"v-2"}`)
+
+	resources, err := ExportAs[fieldOnly](ctx, client, Options{})
+	require.NoError(t, err)
+	require.Equal(t, []fieldOnly{{ID: "1", Field: "v-1"}, {ID: "2", Field: "v-2"}}, resources)
+}
+
+func TestProjectionOf(t *testing.T) {
+	type embedded struct {
+		Field string `json:"field"`
+		Other string
+	}
+	type resource struct {
+		embedded
+		ID        string    `json:"_id"`
+		Field     string    `json:"field,omitempty"`
+		Nested    fieldOnly `json:"nested"`
+		CreatedAt time.Time `json:"createdAt"`
+		Ignored   string    `json:"-"`
+		private   string
+	}
+
+	require.Equal(t, []string{"field", "Other", "_id", "nested", "createdAt"}, projectionOf(reflect.TypeOf(resource{})))
+	require.Equal(t, []string{"_id", "field"}, projectionOf(reflect.TypeOf(&fieldOnly{})))
+	require.Nil(t, projectionOf(reflect.TypeOf(map[string]any{})))
+	require.Nil(t, projectionOf(reflect.TypeOf(struct{}{})))
+}
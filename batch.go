@@ -0,0 +1,179 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	// maxIDsQueryLength is the max length of the escaped ids in the query of a
+	// chunk of GetByIDs, to stay well under the URL length limits of the proxies.
+	maxIDsQueryLength = 4096
+	// getByIDsConcurrency is the max number of chunks of GetByIDs listed at the same time.
+	getByIDsConcurrency = 4
+)
+
+// GetByIDs gets the resources with the given ids, listing them with `_id` `$in` queries.
+// The ids are split in chunks that keep the URL short and that are listed concurrently.
+// The resources are returned in the order of the ids, duplicated ids are returned once,
+// while the ids without a resource are returned as not found.
+// The ids that are valid ObjectIds are matched case insensitively: they are sent both
+// as ObjectId and as given, to find also the string ids with upper case hex digits.
+// The filter of the options is added to the `_id` condition, while skip and limit are replaced.
+func (c Client[Resource]) GetByIDs(ctx context.Context, ids []string, options Options) ([]Resource, []string, error) {
+	keys := make([]string, 0, len(ids))
+	unique := make([]string, 0, len(ids))
+	requested := map[string]string{}
+	for _, id := range ids {
+		key := normalizeID(id)
+		if _, ok := requested[key]; ok {
+			continue
+		}
+		requested[key] = id
+		keys = append(keys, key)
+		unique = append(unique, id)
+	}
+
+	chunks := chunkIDs(unique)
+	results := make([][]Resource, len(chunks))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	semaphore := make(chan struct{}, getByIDsConcurrency)
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk []any) {
+			defer wg.Done()
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-semaphore }()
+
+			chunkOptions := options
+			chunkOptions.Filter.MongoQuery = andMongoQuery(
+				options.Filter.MongoQuery,
+				map[string]any{"_id": map[string]any{"$in": chunk}},
+			)
+			chunkOptions.Filter.Limit = len(chunk)
+			chunkOptions.Filter.Skip = 0
+			chunkOptions.Filter.Projection = projectionWithID(options.Filter.Projection)
+
+			resources, err := c.List(ctx, chunkOptions)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			results[i] = resources
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	byKey := map[string]Resource{}
+	for _, resources := range results {
+		for _, resource := range resources {
+			id, err := resourceID(resource)
+			if err != nil {
+				return nil, nil, err
+			}
+			byKey[resourceIDKey(id)] = resource
+		}
+	}
+
+	found := make([]Resource, 0, len(byKey))
+	notFound := []string{}
+	for _, key := range keys {
+		resource, ok := byKey[key]
+		if !ok {
+			notFound = append(notFound, requested[key])
+			continue
+		}
+		found = append(found, resource)
+	}
+	return found, notFound, nil
+}
+
+// chunkIDs splits the ids in chunks of at most DefaultPageSize values, whose escaped
+// JSON is not longer than maxIDsQueryLength. ObjectIds are sent as ObjectID, and also
+// as given if they have upper case hex digits.
+func chunkIDs(ids []string) [][]any {
+	chunks := [][]any{}
+	chunk := []any{}
+	length := 0
+	for _, id := range ids {
+		values := []any{id}
+		if ObjectID(id).IsValid() {
+			values = []any{ObjectID(id)}
+			if id != strings.ToLower(id) {
+				values = append(values, id)
+			}
+		}
+		// the lower case ObjectId has the same length of the id
+		encoded, _ := json.Marshal(id)
+		idLength := len(values) * (len(url.QueryEscape(string(encoded))) + len(url.QueryEscape(",")))
+
+		if len(chunk) > 0 && (len(chunk)+len(values) > DefaultPageSize || length+idLength > maxIDsQueryLength) {
+			chunks = append(chunks, chunk)
+			chunk = []any{}
+			length = 0
+		}
+		chunk = append(chunk, values...)
+		length += idLength
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// normalizeID returns the lower case ObjectIds, which are case insensitive,
+// and the other ids as they are.
+func normalizeID(id string) string {
+	if ObjectID(id).IsValid() {
+		return strings.ToLower(id)
+	}
+	return id
+}
+
+func resourceIDKey(id json.RawMessage) string {
+	var value string
+	if err := json.Unmarshal(id, &value); err != nil {
+		return string(id)
+	}
+	return normalizeID(value)
+}
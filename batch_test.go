@@ -0,0 +1,146 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	gock "github.com/mia-platform/go-crud-service-client/testhelper/gock"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetByIDs(t *testing.T) {
+	ctx := context.Background()
+	client := getClient(t)
+
+	t.Run("get resources in the order of the ids", func(t *testing.T) {
+		objectID := "5F9B3B9B9C9D4B0001A1B2C3"
+		filter := Filter{
+			MongoQuery: map[string]any{
+				"$and": []any{
+					map[string]any{"field": "v"},
+					map[string]any{"_id": map[string]any{"$in": []string{"id-1", "id-2", strings.ToLower(objectID), objectID, "id-3"}}},
+				},
+			},
+			Limit:      5,
+			Projection: []string{"field", "_id"},
+		}
+
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			AddMatcher(gock.CrudQueryMatcher(t, gock.Filter(filter))).
+			Reply(200).
+			JSON([]TestResource{
+				{ID: "id-3", Field: "v"},
+				{ID: strings.ToLower(objectID), Field: "v"},
+				{ID: "id-1", Field: "v"},
+			})
+
+		resources, notFound, err := client.GetByIDs(ctx, []string{"id-1", "id-2", objectID, "id-1", "id-3"}, Options{
+			Filter: Filter{
+				MongoQuery: map[string]any{"field": "v"},
+				Skip:       10,
+				Projection: []string{"field"},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []TestResource{
+			{ID: "id-1", Field: "v"},
+			{ID: strings.ToLower(objectID), Field: "v"},
+			{ID: "id-3", Field: "v"},
+		}, resources)
+		require.Equal(t, []string{"id-2"}, notFound)
+	})
+
+	t.Run("split ids in chunks", func(t *testing.T) {
+		ids := make([]string, 0, 250)
+		for i := 0; i < 250; i++ {
+			ids = append(ids, fmt.Sprintf("id-%03d", i))
+		}
+
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			MatchParam("_l", "^200$").
+			Reply(200).
+			JSON([]TestResource{{ID: "id-000"}, {ID: "id-199"}})
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			MatchParam("_l", "^50$").
+			MatchParam("_q", regexp.QuoteMeta(`"id-200"`)).
+			Reply(200).
+			JSON([]TestResource{{ID: "id-249"}, {ID: "id-200"}})
+
+		resources, notFound, err := client.GetByIDs(ctx, ids, Options{})
+		require.NoError(t, err)
+		require.Equal(t, []TestResource{{ID: "id-000"}, {ID: "id-199"}, {ID: "id-200"}, {ID: "id-249"}}, resources)
+		require.Len(t, notFound, 246)
+	})
+
+	t.Run("find string ids with upper case hex digits", func(t *testing.T) {
+		hash := "ABCDEF0123456789ABCDEF01"
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			AddMatcher(gock.CrudQueryMatcher(t, gock.Filter{
+				MongoQuery: map[string]any{"_id": map[string]any{"$in": []string{strings.ToLower(hash), hash}}},
+				Limit:      2,
+			})).
+			Reply(200).
+			JSON([]TestResource{{ID: hash}})
+
+		resources, notFound, err := client.GetByIDs(ctx, []string{hash}, Options{})
+		require.NoError(t, err)
+		require.Equal(t, []TestResource{{ID: hash}}, resources)
+		require.Empty(t, notFound)
+	})
+
+	t.Run("no ids", func(t *testing.T) {
+		resources, notFound, err := client.GetByIDs(ctx, nil, Options{})
+		require.NoError(t, err)
+		require.Empty(t, resources)
+		require.Empty(t, notFound)
+	})
+
+	t.Run("throws if a chunk fails", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			Reply(500).
+			AddHeader("Content-Type", "application/json").
+			BodyString(`{"message":"error message"}`)
+
+		_, _, err := client.GetByIDs(ctx, []string{"id-1"}, Options{})
+		require.ErrorIs(t, err, ErrResponse)
+		require.EqualError(t, err, "error message")
+	})
+}
+
+func TestChunkIDs(t *testing.T) {
+	t.Run("keep the query short", func(t *testing.T) {
+		longID := strings.Repeat("a", 1000)
+		chunks := chunkIDs([]string{longID, longID, longID, longID, longID})
+		require.Len(t, chunks, 2)
+		require.Len(t, chunks[0], 4)
+		require.Len(t, chunks[1], 1)
+	})
+
+	t.Run("send ObjectIds", func(t *testing.T) {
+		chunks := chunkIDs([]string{"5f9b3b9b9c9d4b0001a1b2c3", "id"})
+		require.Equal(t, [][]any{{ObjectID("5f9b3b9b9c9d4b0001a1b2c3"), "id"}}, chunks)
+
+		chunks = chunkIDs([]string{"5F9B3B9B9C9D4B0001A1B2C3"})
+		require.Equal(t, [][]any{{ObjectID("5F9B3B9B9C9D4B0001A1B2C3"), "5F9B3B9B9C9D4B0001A1B2C3"}}, chunks)
+	})
+}
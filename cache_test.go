@@ -0,0 +1,91 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRUCache(t *testing.T) {
+	ctx := context.Background()
+
+	get := func(t *testing.T, cache *LRUCache, key string) (string, bool) {
+		t.Helper()
+		value, ok, err := cache.Get(ctx, key)
+		require.NoError(t, err)
+		return string(value), ok
+	}
+
+	t.Run("set, get and delete", func(t *testing.T) {
+		cache := NewLRUCache(10)
+
+		_, ok := get(t, cache, "a")
+		require.False(t, ok)
+
+		require.NoError(t, cache.Set(ctx, "a", []byte("1"), 0))
+		require.NoError(t, cache.Set(ctx, "a", []byte("2"), 0))
+		value, ok := get(t, cache, "a")
+		require.True(t, ok)
+		require.Equal(t, "2", value)
+		require.Equal(t, 1, cache.Len())
+
+		require.NoError(t, cache.Delete(ctx, "a"))
+		_, ok = get(t, cache, "a")
+		require.False(t, ok)
+		require.NoError(t, cache.Delete(ctx, "a"))
+	})
+
+	t.Run("evict the least recently used", func(t *testing.T) {
+		cache := NewLRUCache(2)
+
+		require.NoError(t, cache.Set(ctx, "a", []byte("1"), 0))
+		require.NoError(t, cache.Set(ctx, "b", []byte("2"), 0))
+		get(t, cache, "a")
+		require.NoError(t, cache.Set(ctx, "c", []byte("3"), 0))
+
+		_, ok := get(t, cache, "b")
+		require.False(t, ok)
+		_, ok = get(t, cache, "a")
+		require.True(t, ok)
+		_, ok = get(t, cache, "c")
+		require.True(t, ok)
+		require.Equal(t, 2, cache.Len())
+	})
+
+	t.Run("expire entries", func(t *testing.T) {
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		cache := NewLRUCache(10)
+		cache.now = func() time.Time { return now }
+
+		require.NoError(t, cache.Set(ctx, "a", []byte("1"), time.Minute))
+		require.NoError(t, cache.Set(ctx, "b", []byte("2"), 0))
+
+		now = now.Add(59 * time.Second)
+		_, ok := get(t, cache, "a")
+		require.True(t, ok)
+
+		now = now.Add(time.Second)
+		_, ok = get(t, cache, "a")
+		require.False(t, ok)
+		_, ok = get(t, cache, "b")
+		require.True(t, ok)
+		require.Equal(t, 1, cache.Len())
+	})
+}
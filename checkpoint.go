@@ -0,0 +1,94 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// CheckpointStore persists the checkpoint of a long running operation, like a
+// resumable export, so that it can continue from there after a failure or a restart.
+// The checkpoint is opaque to the store.
+type CheckpointStore interface {
+	// Load returns the saved checkpoint, or nil if there is none.
+	Load(ctx context.Context) ([]byte, error)
+	// Save replaces the saved checkpoint.
+	Save(ctx context.Context, checkpoint []byte) error
+	// Clear removes the saved checkpoint, if any.
+	Clear(ctx context.Context) error
+}
+
+// FileCheckpointStore is a CheckpointStore that saves the checkpoint in a file.
+// The file is replaced atomically, so an interrupted Save leaves the previous checkpoint.
+type FileCheckpointStore struct {
+	path string
+}
+
+// NewFileCheckpointStore creates a FileCheckpointStore that saves the checkpoint in
+// the file at path. The directory of the file must exist.
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path}
+}
+
+// Load returns the content of the file, or nil if it does not exist.
+func (s *FileCheckpointStore) Load(_ context.Context) ([]byte, error) {
+	checkpoint, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: %s", ErrCheckpoint, err)
+	}
+	return checkpoint, nil
+}
+
+// Save writes the checkpoint in a temporary file that then replaces the file.
+func (s *FileCheckpointStore) Save(_ context.Context, checkpoint []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrCheckpoint, err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(checkpoint); err != nil {
+		file.Close()
+		return fmt.Errorf("%w: %s", ErrCheckpoint, err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("%w: %s", ErrCheckpoint, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("%w: %s", ErrCheckpoint, err)
+	}
+	if err := os.Rename(file.Name(), s.path); err != nil {
+		return fmt.Errorf("%w: %s", ErrCheckpoint, err)
+	}
+	return nil
+}
+
+// Clear removes the file.
+func (s *FileCheckpointStore) Clear(_ context.Context) error {
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrCheckpoint, err)
+	}
+	return nil
+}
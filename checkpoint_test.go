@@ -0,0 +1,67 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileCheckpointStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("load without checkpoint", func(t *testing.T) {
+		store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"))
+
+		checkpoint, err := store.Load(ctx)
+		require.NoError(t, err)
+		require.Nil(t, checkpoint)
+	})
+
+	t.Run("save, load and clear checkpoint", func(t *testing.T) {
+		dir := t.TempDir()
+		store := NewFileCheckpointStore(filepath.Join(dir, "checkpoint.json"))
+
+		require.NoError(t, store.Save(ctx, []byte(`{"lastId":"1"}`)))
+		require.NoError(t, store.Save(ctx, []byte(`{"lastId":"2"}`)))
+
+		checkpoint, err := store.Load(ctx)
+		require.NoError(t, err)
+		require.Equal(t, `{"lastId":"2"}`, string(checkpoint))
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+
+		require.NoError(t, store.Clear(ctx))
+		checkpoint, err = store.Load(ctx)
+		require.NoError(t, err)
+		require.Nil(t, checkpoint)
+
+		require.NoError(t, store.Clear(ctx))
+	})
+
+	t.Run("throws if directory does not exist", func(t *testing.T) {
+		store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "missing", "checkpoint.json"))
+
+		err := store.Save(ctx, []byte(`{}`))
+		require.ErrorIs(t, err, ErrCheckpoint)
+	})
+}
@@ -16,11 +16,9 @@
 package crud
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"strconv"
 
@@ -28,25 +26,66 @@ import (
 )
 
 type Client[Resource any] struct {
-	client *jsonclient.Client
+	client      *jsonclient.Client
+	httpClient  *http.Client
+	queryPolicy *QueryPolicy
+	flights     *flightGroup
+	cache       *clientCache
 }
 
 // NewClient create a new client to interact with crud-service
 func NewClient[Resource any](options ClientOptions) (CrudClient[Resource], error) {
+	httpClient := http.DefaultClient
 	client, err := jsonclient.New(jsonclient.Options{
-		BaseURL: options.BaseURL,
-		Headers: options.convertHeaders(),
+		BaseURL:    options.BaseURL,
+		Headers:    options.convertHeaders(),
+		HTTPClient: httpClient,
 	})
 	if err != nil {
 		return Client[Resource]{}, fmt.Errorf("%w: %s", ErrCreateClient, err)
 	}
+	var flights *flightGroup
+	if options.DeduplicateReads {
+		flights = newFlightGroup()
+	}
 	return Client[Resource]{
-		client: client,
+		client:      client,
+		httpClient:  httpClient,
+		queryPolicy: options.QueryPolicy,
+		flights:     flights,
+		cache:       newClientCache(options.Cache, options.BaseURL),
 	}, err
 }
 
+func (c Client[Resource]) checkQueryPolicy(options Options, requireLimit bool, queries ...map[string]any) error {
+	if c.queryPolicy == nil {
+		return nil
+	}
+	if err := c.queryPolicy.Check(options.Filter, requireLimit); err != nil {
+		return err
+	}
+	for _, query := range queries {
+		if err := c.queryPolicy.CheckMongoQuery(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GetById get a resource by _id
 func (c Client[Resource]) GetByID(ctx context.Context, id string, options Options) (*Resource, error) {
+	return cachedRead(ctx, c.cache, cacheOperationGetByID, id, options, func() (*Resource, error) {
+		return deduplicate(ctx, c.flights, "getById", id, options, func(ctx context.Context) (*Resource, error) {
+			return c.getByID(ctx, id, options)
+		}, cloneJSON[*Resource])
+	})
+}
+
+func (c Client[Resource]) getByID(ctx context.Context, id string, options Options) (*Resource, error) {
+	if err := c.checkQueryPolicy(options, false); err != nil {
+		return nil, err
+	}
+
 	req, err := c.client.NewRequestWithContext(ctx, http.MethodGet, id, nil)
 	if err != nil {
 		return nil, err
@@ -67,6 +106,18 @@ func (c Client[Resource]) GetByID(ctx context.Context, id string, options Option
 // and with a max page of 200 elements (by default).
 // If you want to take more elements, use pagination
 func (c Client[Resource]) List(ctx context.Context, options Options) ([]Resource, error) {
+	return cachedRead(ctx, c.cache, cacheOperationList, "", options, func() ([]Resource, error) {
+		return deduplicate(ctx, c.flights, "list", "", options, func(ctx context.Context) ([]Resource, error) {
+			return c.list(ctx, options)
+		}, cloneJSON[[]Resource])
+	})
+}
+
+func (c Client[Resource]) list(ctx context.Context, options Options) ([]Resource, error) {
+	if err := c.checkQueryPolicy(options, true); err != nil {
+		return nil, err
+	}
+
 	req, err := c.client.NewRequestWithContext(ctx, http.MethodGet, "", nil)
 	if err != nil {
 		return nil, err
@@ -85,6 +136,18 @@ func (c Client[Resource]) List(ctx context.Context, options Options) ([]Resource
 
 // Count resources
 func (c Client[Resource]) Count(ctx context.Context, options Options) (int, error) {
+	return cachedRead(ctx, c.cache, cacheOperationCount, "", options, func() (int, error) {
+		return deduplicate(ctx, c.flights, "count", "", options, func(ctx context.Context) (int, error) {
+			return c.count(ctx, options)
+		}, nil)
+	})
+}
+
+func (c Client[Resource]) count(ctx context.Context, options Options) (int, error) {
+	if err := c.checkQueryPolicy(options, false); err != nil {
+		return 0, err
+	}
+
 	req, err := c.client.NewRequestWithContext(ctx, http.MethodGet, "count", nil)
 	if err != nil {
 		return 0, err
@@ -102,37 +165,22 @@ func (c Client[Resource]) Count(ctx context.Context, options Options) (int, erro
 }
 
 // Export calls /export endpoint of crud-service. It is possible to add filters.
-// Exports does not have max limits.
+// Exports does not have max limits. To avoid to keep all the resources in memory,
+// use ExportStream.
 func (c Client[Resource]) Export(ctx context.Context, options Options) ([]Resource, error) {
-	req, err := c.client.NewRequestWithContext(ctx, http.MethodGet, "export", nil)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %s", ErrCreateRequest, err)
-	}
-
-	if err := options.setOptionsInRequest(req); err != nil {
-		return nil, err
-	}
-
-	responseBuf := bytes.NewBuffer(nil)
-	_, err = c.client.Do(req, responseBuf)
-	if err != nil {
-		return nil, responseError(err)
-	}
+	return deduplicate(ctx, c.flights, "export", "", options, func(ctx context.Context) ([]Resource, error) {
+		return c.export(ctx, options)
+	}, cloneJSON[[]Resource])
+}
 
+func (c Client[Resource]) export(ctx context.Context, options Options) ([]Resource, error) {
 	resources := []Resource{}
-
-	decoder := json.NewDecoder(responseBuf)
-	for decoder.More() {
-		resource := new(Resource)
-		if err := decoder.Decode(resource); err != nil {
-			if err != io.EOF {
-				return nil, err
-			}
-			break
-		}
-		resources = append(resources, *resource)
+	if _, err := c.ExportStream(ctx, options, func(resource Resource) error {
+		resources = append(resources, resource)
+		return nil
+	}); err != nil {
+		return nil, err
 	}
-
 	return resources, nil
 }
 
@@ -161,6 +209,11 @@ type PatchBody struct {
 
 // PatchById update an element using commands in PatchBody
 func (c Client[Resource]) PatchById(ctx context.Context, id string, body PatchBody, options Options) (*Resource, error) {
+	if err := c.checkQueryPolicy(options, false); err != nil {
+		return nil, err
+	}
+	defer c.cache.invalidateID(ctx, id)
+
 	req, err := c.client.NewRequestWithContext(ctx, http.MethodPatch, id, body)
 	if err != nil {
 		return nil, err
@@ -179,6 +232,11 @@ func (c Client[Resource]) PatchById(ctx context.Context, id string, body PatchBo
 
 // PatchMany updates resources using commands in PatchBody
 func (c Client[Resource]) PatchMany(ctx context.Context, body PatchBody, options Options) (int, error) {
+	if err := c.checkQueryPolicy(options, false); err != nil {
+		return 0, err
+	}
+	defer c.cache.invalidateAll(ctx)
+
 	req, err := c.client.NewRequestWithContext(ctx, http.MethodPatch, "", body)
 	if err != nil {
 		return 0, err
@@ -260,6 +318,15 @@ type PatchBulkBody []PatchBulkItem
 
 // PatchBulk updates multiple resources, each one with its own modifications
 func (c Client[Resource]) PatchBulk(ctx context.Context, body PatchBulkBody, options Options) (int, error) {
+	queries := make([]map[string]any, 0, len(body))
+	for _, item := range body {
+		queries = append(queries, item.Filter.MongoQuery)
+	}
+	if err := c.checkQueryPolicy(options, false, queries...); err != nil {
+		return 0, err
+	}
+	defer c.cache.invalidateAll(ctx)
+
 	req, err := c.client.NewRequestWithContext(ctx, http.MethodPatch, "bulk", body)
 	if err != nil {
 		return 0, err
@@ -284,6 +351,11 @@ type CreatedResource struct {
 // Create performs a POST request to create a new resource on the target crud. Returns the
 // identifier of the created resource and any error that occurred.
 func (c Client[Resource]) Create(ctx context.Context, resource Resource, options Options) (string, error) {
+	if err := c.checkQueryPolicy(options, false); err != nil {
+		return "", err
+	}
+	defer c.cache.invalidateAll(ctx)
+
 	req, err := c.client.NewRequestWithContext(ctx, http.MethodPost, "", resource)
 	if err != nil {
 		return "", err
@@ -303,6 +375,11 @@ func (c Client[Resource]) Create(ctx context.Context, resource Resource, options
 // Create performs a POST request to create new resources on the target crud. Returns the
 // identifier of the created resources and any error that occurred.
 func (c Client[Resource]) CreateMany(ctx context.Context, resources []Resource, options Options) ([]CreatedResource, error) {
+	if err := c.checkQueryPolicy(options, false); err != nil {
+		return []CreatedResource{}, err
+	}
+	defer c.cache.invalidateAll(ctx)
+
 	req, err := c.client.NewRequestWithContext(ctx, http.MethodPost, "bulk", resources)
 	if err != nil {
 		return []CreatedResource{}, err
@@ -321,6 +398,11 @@ func (c Client[Resource]) CreateMany(ctx context.Context, resources []Resource,
 
 // DeleteById deletes an element using the resource _id.
 func (c Client[Resource]) DeleteById(ctx context.Context, id string, options Options) error {
+	if err := c.checkQueryPolicy(options, false); err != nil {
+		return err
+	}
+	defer c.cache.invalidateID(ctx, id)
+
 	req, err := c.client.NewRequestWithContext(ctx, http.MethodDelete, id, nil)
 	if err != nil {
 		return err
@@ -338,6 +420,11 @@ func (c Client[Resource]) DeleteById(ctx context.Context, id string, options Opt
 
 // DeleteMany allow to remove multiple resources.
 func (c Client[Resource]) DeleteMany(ctx context.Context, options Options) (int, error) {
+	if err := c.checkQueryPolicy(options, false); err != nil {
+		return 0, err
+	}
+	defer c.cache.invalidateAll(ctx)
+
 	req, err := c.client.NewRequestWithContext(ctx, http.MethodDelete, "", nil)
 	if err != nil {
 		return 0, err
@@ -381,6 +468,11 @@ type UpsertBody struct {
 
 // UpsertOne allow to remove multiple resources.
 func (c Client[Resource]) UpsertOne(ctx context.Context, body UpsertBody, options Options) (*Resource, error) {
+	if err := c.checkQueryPolicy(options, false); err != nil {
+		return nil, err
+	}
+	defer c.cache.invalidateAll(ctx)
+
 	req, err := c.client.NewRequestWithContext(ctx, http.MethodPost, "upsert-one", body)
 	if err != nil {
 		return nil, err
@@ -208,6 +208,21 @@ func TestList(t *testing.T) {
 		require.Equal(t, expectedElements, resources)
 	})
 
+	t.Run("list element with state", func(t *testing.T) {
+		filter := Filter{
+			State: []State{StatePublic, StateDraft},
+		}
+
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			AddMatcher(gock.CrudQueryMatcher(t, gock.Filter(filter))).
+			Reply(200).
+			JSON(expectedElements)
+
+		resources, err := client.List(ctx, Options{Filter: filter})
+		require.NoError(t, err)
+		require.Equal(t, expectedElements, resources)
+	})
+
 	t.Run("throws - not found", func(t *testing.T) {
 		gock.NewGockScope(t, baseURL, http.MethodGet, "").
 			Reply(404).
@@ -1036,6 +1051,20 @@ func TestDelete(t *testing.T) {
 		require.Equal(t, 3, n)
 	})
 
+	t.Run("delete element with state", func(t *testing.T) {
+		filter := Filter{
+			State: []State{StateTrash},
+		}
+
+		gock.NewGockScope(t, baseURL, http.MethodDelete, "").
+			AddMatcher(gock.CrudQueryMatcher(t, gock.Filter(filter))).
+			Reply(200).BodyString("3")
+
+		n, err := client.DeleteMany(ctx, Options{Filter: filter})
+		require.NoError(t, err)
+		require.Equal(t, 3, n)
+	})
+
 	t.Run("throws - not found", func(t *testing.T) {
 		gock.NewGockScope(t, baseURL, http.MethodDelete, "").
 			Reply(404).
@@ -20,6 +20,14 @@ import "net/http"
 type ClientOptions struct {
 	BaseURL string
 	Headers http.Header
+	// QueryPolicy, if set, is checked before sending each request to crud-service
+	QueryPolicy *QueryPolicy
+	// DeduplicateReads makes identical concurrent GetByID, List, Count and Export calls
+	// share a single request and its result. Calls are identical if they have the same
+	// id, filter and headers. It can be disabled per call with Options.SkipDeduplication.
+	DeduplicateReads bool
+	// Cache, if set, caches the results of GetByID, List and Count
+	Cache *CacheOptions
 }
 
 func (options ClientOptions) convertHeaders() map[string]string {
@@ -0,0 +1,292 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/mia-platform/go-crud-service-client/internal/types"
+)
+
+// CursorOptions configures a CursorPager.
+type CursorOptions struct {
+	// SortKeys are the fields used to sort the documents, which together must identify
+	// a document. A key prefixed by `-` is sorted in descending order. Default is `_id`.
+	SortKeys []string
+	// PageSize is the max number of resources in a page. Default is DefaultPageSize.
+	PageSize int
+	// Secret signs the cursors, so that a cursor modified by the user is rejected.
+	// If empty, a random key generated once per process is used: the cursors are
+	// then valid only in the process that created them.
+	Secret []byte
+}
+
+var (
+	processCursorSecret     []byte
+	processCursorSecretOnce sync.Once
+)
+
+func cursorSecret(secret []byte) []byte {
+	if len(secret) != 0 {
+		return secret
+	}
+	processCursorSecretOnce.Do(func() {
+		processCursorSecret = make([]byte, 32)
+		if _, err := rand.Read(processCursorSecret); err != nil {
+			panic(fmt.Sprintf("crud: cannot generate the cursor secret: %s", err))
+		}
+	})
+	return processCursorSecret
+}
+
+// CursorPage is a page of resources returned by CursorPager.
+type CursorPage[Resource any] struct {
+	Items []Resource
+	// NextCursor is the cursor of the next page, empty if there are no more resources
+	NextCursor string
+	// PrevCursor is the cursor of the previous page, empty if this is the first page
+	PrevCursor string
+}
+
+// CursorPager lists the resources with keyset pagination: instead of skipping the
+// resources of the previous pages, it adds a condition on the sort keys to the query.
+// The cursors are opaque and signed, so they can be returned to the users of an API.
+type CursorPager[Resource any] struct {
+	client   CrudClient[Resource]
+	keys     []cursorKey
+	pageSize int
+	secret   []byte
+}
+
+type cursorKey struct {
+	field      string
+	descending bool
+}
+
+// cursor is the content of the tokens returned by CursorPager
+type cursor struct {
+	Values   []any `json:"v"`
+	Backward bool  `json:"b,omitempty"`
+}
+
+// NewCursorPager creates a CursorPager using the client.
+func NewCursorPager[Resource any](client CrudClient[Resource], options CursorOptions) *CursorPager[Resource] {
+	sortKeys := options.SortKeys
+	if len(sortKeys) == 0 {
+		sortKeys = []string{"_id"}
+	}
+	keys := make([]cursorKey, 0, len(sortKeys))
+	for _, key := range sortKeys {
+		keys = append(keys, cursorKey{field: strings.TrimPrefix(key, "-"), descending: strings.HasPrefix(key, "-")})
+	}
+
+	pageSize := options.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	return &CursorPager[Resource]{
+		client:   client,
+		keys:     keys,
+		pageSize: pageSize,
+		secret:   cursorSecret(options.Secret),
+	}
+}
+
+// Page returns the page identified by the cursor, or the first page if the cursor is
+// empty. The filter of options is applied, while its Limit, Skip and Sort are replaced.
+// A cursor is valid only with the same filter of the page that returned it.
+func (p *CursorPager[Resource]) Page(ctx context.Context, token string, options Options) (CursorPage[Resource], error) {
+	scope, err := cursorScope(options.Filter)
+	if err != nil {
+		return CursorPage[Resource]{}, err
+	}
+
+	var current *cursor
+	if token != "" {
+		decoded, err := p.decodeCursor(token, scope)
+		if err != nil {
+			return CursorPage[Resource]{}, err
+		}
+		current = &decoded
+	}
+	backward := current != nil && current.Backward
+
+	listOptions := options
+	listOptions.Filter.Skip = 0
+	// one more resource is requested to know if there is another page
+	listOptions.Filter.Limit = p.pageSize + 1
+	listOptions.Filter.Sort = p.sort(backward)
+	if current != nil {
+		listOptions.Filter.MongoQuery = andMongoQuery(options.Filter.MongoQuery, p.keysetCondition(current.Values, backward))
+	}
+
+	resources, err := p.client.List(ctx, listOptions)
+	if err != nil {
+		return CursorPage[Resource]{}, err
+	}
+
+	hasMore := len(resources) > p.pageSize
+	if hasMore {
+		resources = resources[:p.pageSize]
+	}
+	if backward {
+		for i, j := 0, len(resources)-1; i < j; i, j = i+1, j-1 {
+			resources[i], resources[j] = resources[j], resources[i]
+		}
+	}
+
+	page := CursorPage[Resource]{Items: resources}
+	if len(resources) == 0 {
+		return page, nil
+	}
+
+	hasNext := hasMore
+	hasPrev := current != nil
+	if backward {
+		hasNext, hasPrev = true, hasMore
+	}
+
+	if hasNext {
+		if page.NextCursor, err = p.cursorOf(resources[len(resources)-1], false, scope); err != nil {
+			return CursorPage[Resource]{}, err
+		}
+	}
+	if hasPrev {
+		if page.PrevCursor, err = p.cursorOf(resources[0], true, scope); err != nil {
+			return CursorPage[Resource]{}, err
+		}
+	}
+	return page, nil
+}
+
+func (p *CursorPager[Resource]) sort(backward bool) string {
+	fields := make([]string, 0, len(p.keys))
+	for _, key := range p.keys {
+		if key.descending != backward {
+			fields = append(fields, "-"+key.field)
+		} else {
+			fields = append(fields, key.field)
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+// keysetCondition returns the condition to get the documents after the values in the
+// sort order, or before them if backward is true. For the keys (a, b) it is
+// `a > va OR (a = va AND b > vb)`.
+func (p *CursorPager[Resource]) keysetCondition(values []any, backward bool) map[string]any {
+	branches := make([]any, 0, len(p.keys))
+	for i, key := range p.keys {
+		branch := map[string]any{}
+		for j := 0; j < i; j++ {
+			branch[p.keys[j].field] = values[j]
+		}
+		operator := "$gt"
+		if key.descending != backward {
+			operator = "$lt"
+		}
+		branch[key.field] = map[string]any{operator: values[i]}
+		branches = append(branches, branch)
+	}
+
+	if len(branches) == 1 {
+		return branches[0].(map[string]any)
+	}
+	return map[string]any{"$or": branches}
+}
+
+// cursorScope returns the canonical query of the filter, excluding the options
+// replaced by the pager, so that a cursor cannot be used with another filter.
+func cursorScope(filter Filter) (string, error) {
+	filter.Limit = 0
+	filter.Skip = 0
+	filter.Sort = ""
+	query := url.Values{}
+	if err := convertFilter(query, types.Filter(filter)); err != nil {
+		return "", err
+	}
+	return query.Encode(), nil
+}
+
+func (p *CursorPager[Resource]) cursorOf(resource Resource, backward bool, scope string) (string, error) {
+	document, err := normalizeJSON(resource)
+	if err != nil {
+		return "", err
+	}
+
+	values := make([]any, 0, len(p.keys))
+	for _, key := range p.keys {
+		found := lookupPath(document, key.field)
+		if len(found) != 1 {
+			return "", fmt.Errorf("%w: sort key %s not found in resource", ErrInvalidCursor, key.field)
+		}
+		values = append(values, found[0])
+	}
+	return p.encodeCursor(cursor{Values: values, Backward: backward}, scope)
+}
+
+func (p *CursorPager[Resource]) encodeCursor(c cursor, scope string) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(p.sign(encoded, scope)), nil
+}
+
+func (p *CursorPager[Resource]) decodeCursor(token, scope string) (cursor, error) {
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found {
+		return cursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidCursor)
+	}
+	decodedSignature, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(decodedSignature, p.sign(encoded, scope)) {
+		return cursor{}, fmt.Errorf("%w: invalid signature", ErrInvalidCursor)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidCursor)
+	}
+	var c cursor
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&c); err != nil || len(c.Values) != len(p.keys) {
+		return cursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidCursor)
+	}
+	return c, nil
+}
+
+func (p *CursorPager[Resource]) sign(payload, scope string) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(p.sort(false)))
+	mac.Write([]byte{0})
+	mac.Write([]byte(scope))
+	mac.Write([]byte{0})
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,211 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	gock "github.com/mia-platform/go-crud-service-client/testhelper/gock"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorPager(t *testing.T) {
+	ctx := context.Background()
+	client := getClient(t)
+
+	secret := []byte("secret")
+	baseQuery := map[string]any{"field": "v"}
+
+	t.Run("page forward and backward by _id", func(t *testing.T) {
+		pager := NewCursorPager[TestResource](client, CursorOptions{PageSize: 2, Secret: secret})
+
+		mockListPage(t, Filter{Limit: 3, Sort: "_id", MongoQuery: baseQuery}, []TestResource{{ID: "1"}, {ID: "2"}, {ID: "3"}})
+		first, err := pager.Page(ctx, "", Options{Filter: Filter{MongoQuery: baseQuery, Skip: 10}})
+		require.NoError(t, err)
+		require.Equal(t, []TestResource{{ID: "1"}, {ID: "2"}}, first.Items)
+		require.NotEmpty(t, first.NextCursor)
+		require.Empty(t, first.PrevCursor)
+
+		mockListPage(t, Filter{
+			Limit: 3,
+			Sort:  "_id",
+			MongoQuery: map[string]any{"$and": []any{
+				baseQuery,
+				map[string]any{"_id": map[string]any{"$gt": "2"}},
+			}},
+		}, []TestResource{{ID: "3"}, {ID: "4"}})
+		second, err := pager.Page(ctx, first.NextCursor, Options{Filter: Filter{MongoQuery: baseQuery}})
+		require.NoError(t, err)
+		require.Equal(t, []TestResource{{ID: "3"}, {ID: "4"}}, second.Items)
+		require.Empty(t, second.NextCursor)
+		require.NotEmpty(t, second.PrevCursor)
+
+		mockListPage(t, Filter{
+			Limit: 3,
+			Sort:  "-_id",
+			MongoQuery: map[string]any{"$and": []any{
+				baseQuery,
+				map[string]any{"_id": map[string]any{"$lt": "3"}},
+			}},
+		}, []TestResource{{ID: "2"}, {ID: "1"}})
+		back, err := pager.Page(ctx, second.PrevCursor, Options{Filter: Filter{MongoQuery: baseQuery}})
+		require.NoError(t, err)
+		require.Equal(t, []TestResource{{ID: "1"}, {ID: "2"}}, back.Items)
+		require.NotEmpty(t, back.NextCursor)
+		require.Empty(t, back.PrevCursor)
+	})
+
+	t.Run("compound and descending keys", func(t *testing.T) {
+		pager := NewCursorPager[TestResource](client, CursorOptions{
+			SortKeys: []string{"-intField", "_id"},
+			PageSize: 1,
+			Secret:   secret,
+		})
+
+		mockListPage(t, Filter{Limit: 2, Sort: "-intField,_id"}, []TestResource{{ID: "a", IntField: 5}, {ID: "b", IntField: 4}})
+		first, err := pager.Page(ctx, "", Options{})
+		require.NoError(t, err)
+		require.Equal(t, []TestResource{{ID: "a", IntField: 5}}, first.Items)
+
+		mockListPage(t, Filter{
+			Limit: 2,
+			Sort:  "-intField,_id",
+			MongoQuery: map[string]any{"$or": []any{
+				map[string]any{"intField": map[string]any{"$lt": 5}},
+				map[string]any{"intField": 5, "_id": map[string]any{"$gt": "a"}},
+			}},
+		}, []TestResource{{ID: "b", IntField: 4}, {ID: "c", IntField: 3}})
+		second, err := pager.Page(ctx, first.NextCursor, Options{})
+		require.NoError(t, err)
+		require.Equal(t, []TestResource{{ID: "b", IntField: 4}}, second.Items)
+		require.NotEmpty(t, second.NextCursor)
+		require.NotEmpty(t, second.PrevCursor)
+
+		mockListPage(t, Filter{
+			Limit: 2,
+			Sort:  "intField,-_id",
+			MongoQuery: map[string]any{"$or": []any{
+				map[string]any{"intField": map[string]any{"$gt": 4}},
+				map[string]any{"intField": 4, "_id": map[string]any{"$lt": "b"}},
+			}},
+		}, []TestResource{{ID: "a", IntField: 5}})
+		back, err := pager.Page(ctx, second.PrevCursor, Options{})
+		require.NoError(t, err)
+		require.Equal(t, []TestResource{{ID: "a", IntField: 5}}, back.Items)
+		require.Empty(t, back.PrevCursor)
+		require.NotEmpty(t, back.NextCursor)
+	})
+
+	t.Run("empty page", func(t *testing.T) {
+		pager := NewCursorPager[TestResource](client, CursorOptions{Secret: secret})
+
+		mockListPage(t, Filter{Limit: DefaultPageSize + 1, Sort: "_id"}, []TestResource{})
+		page, err := pager.Page(ctx, "", Options{})
+		require.NoError(t, err)
+		require.Equal(t, CursorPage[TestResource]{Items: []TestResource{}}, page)
+	})
+
+	t.Run("reject tampered cursor", func(t *testing.T) {
+		pager := NewCursorPager[TestResource](client, CursorOptions{PageSize: 1, Secret: secret})
+
+		mockListPage(t, Filter{Limit: 2, Sort: "_id"}, []TestResource{{ID: "1"}, {ID: "2"}})
+		first, err := pager.Page(ctx, "", Options{})
+		require.NoError(t, err)
+
+		payload, signature, _ := strings.Cut(first.NextCursor, ".")
+		tests := []string{
+			"not-a-cursor",
+			payload + "x." + signature,
+			payload + "." + signature + "x",
+		}
+		for _, token := range tests {
+			_, err := pager.Page(ctx, token, Options{})
+			require.ErrorIs(t, err, ErrInvalidCursor)
+		}
+
+		otherPager := NewCursorPager[TestResource](client, CursorOptions{PageSize: 1, Secret: []byte("other")})
+		_, err = otherPager.Page(ctx, first.NextCursor, Options{})
+		require.EqualError(t, err, "invalid cursor: invalid signature")
+
+		otherSortPager := NewCursorPager[TestResource](client, CursorOptions{PageSize: 1, Secret: secret, SortKeys: []string{"field", "_id"}})
+		_, err = otherSortPager.Page(ctx, first.NextCursor, Options{})
+		require.EqualError(t, err, "invalid cursor: invalid signature")
+
+		_, err = pager.Page(ctx, first.NextCursor, Options{Filter: Filter{MongoQuery: baseQuery}})
+		require.EqualError(t, err, "invalid cursor: invalid signature")
+		_, err = pager.Page(ctx, first.NextCursor, Options{Filter: Filter{Fields: map[string]string{"field": "v"}}})
+		require.EqualError(t, err, "invalid cursor: invalid signature")
+	})
+
+	t.Run("cursor is bound to the filter but not to limit, skip and sort", func(t *testing.T) {
+		pager := NewCursorPager[TestResource](client, CursorOptions{PageSize: 1, Secret: secret})
+
+		mockListPage(t, Filter{Limit: 2, Sort: "_id", MongoQuery: baseQuery}, []TestResource{{ID: "1"}, {ID: "2"}})
+		first, err := pager.Page(ctx, "", Options{Filter: Filter{MongoQuery: baseQuery, Limit: 7, Sort: "field"}})
+		require.NoError(t, err)
+
+		mockListPage(t, Filter{
+			Limit: 2,
+			Sort:  "_id",
+			MongoQuery: map[string]any{"$and": []any{
+				baseQuery,
+				map[string]any{"_id": map[string]any{"$gt": "1"}},
+			}},
+		}, []TestResource{{ID: "2"}})
+		second, err := pager.Page(ctx, first.NextCursor, Options{Filter: Filter{MongoQuery: baseQuery, Skip: 3}})
+		require.NoError(t, err)
+		require.Equal(t, []TestResource{{ID: "2"}}, second.Items)
+	})
+
+	t.Run("without secret cursors are signed with a random key", func(t *testing.T) {
+		pager := NewCursorPager[TestResource](client, CursorOptions{PageSize: 1})
+		require.Len(t, pager.secret, 32)
+		require.Equal(t, pager.secret, NewCursorPager[TestResource](client, CursorOptions{}).secret)
+
+		mockListPage(t, Filter{Limit: 2, Sort: "_id"}, []TestResource{{ID: "1"}, {ID: "2"}})
+		first, err := pager.Page(ctx, "", Options{})
+		require.NoError(t, err)
+
+		emptyKeyPager := &CursorPager[TestResource]{client: client, keys: pager.keys, pageSize: 1}
+		forged, err := emptyKeyPager.encodeCursor(cursor{Values: []any{"1"}}, "")
+		require.NoError(t, err)
+		require.NotEqual(t, first.NextCursor, forged)
+		_, err = pager.Page(ctx, forged, Options{})
+		require.EqualError(t, err, "invalid cursor: invalid signature")
+	})
+
+	t.Run("sort key missing in resource", func(t *testing.T) {
+		pager := NewCursorPager[TestResource](client, CursorOptions{PageSize: 1, SortKeys: []string{"missing"}})
+
+		mockListPage(t, Filter{Limit: 2, Sort: "missing"}, []TestResource{{ID: "1"}, {ID: "2"}})
+		_, err := pager.Page(ctx, "", Options{})
+		require.EqualError(t, err, "invalid cursor: sort key missing not found in resource")
+	})
+
+	t.Run("client error", func(t *testing.T) {
+		pager := NewCursorPager[TestResource](client, CursorOptions{})
+
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			Reply(500).
+			JSON(CrudErrorResponse{Message: "broken", StatusCode: 500})
+		_, err := pager.Page(ctx, "", Options{})
+		require.EqualError(t, err, "broken")
+	})
+}
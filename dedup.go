@@ -0,0 +1,172 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/mia-platform/go-crud-service-client/internal/types"
+)
+
+// flightGroup tracks the requests in flight, so that identical concurrent
+// requests share the first one.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+	shared  bool
+
+	value      any
+	err        error
+	panicked   bool
+	panicValue any
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{calls: map[string]*flightCall{}}
+}
+
+// do calls fn, unless a call with the same key is in flight: in this case, it
+// waits for its result and reports that the result is shared.
+// fn runs on a context that keeps the values of the first caller but is not
+// cancelled with it: each caller returns as soon as its own context is done,
+// and the call is cancelled only when all the callers returned.
+// If fn panics, the panic is propagated to all the callers.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (any, error)) (any, error, bool) {
+	g.mu.Lock()
+	call, ok := g.calls[key]
+	if ok {
+		call.shared = true
+	} else {
+		callCtx, cancel := context.WithCancel(detachedContext{Context: ctx})
+		call = &flightCall{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = call
+		go g.run(callCtx, key, call, fn)
+	}
+	call.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		if call.panicked {
+			panic(call.panicValue)
+		}
+		g.mu.Lock()
+		shared := call.shared
+		g.mu.Unlock()
+		return call.value, call.err, shared
+	case <-ctx.Done():
+		g.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			g.forget(key, call)
+			call.cancel()
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err(), false
+	}
+}
+
+func (g *flightGroup) run(ctx context.Context, key string, call *flightCall, fn func(ctx context.Context) (any, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			call.panicked = true
+			call.panicValue = r
+		}
+		g.mu.Lock()
+		g.forget(key, call)
+		g.mu.Unlock()
+		call.cancel()
+		close(call.done)
+	}()
+
+	call.value, call.err = fn(ctx)
+}
+
+// forget removes the call, so that the next calls with the same key do a new
+// request. It must be called with the lock held.
+func (g *flightGroup) forget(key string, call *flightCall) {
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+}
+
+// deduplicate calls fn sharing the result with the identical calls in flight.
+// The request is not cancelled when a caller returns because its context is done,
+// but only when all the callers returned. The shared results are deep cloned, so
+// that the callers do not modify each other's result.
+func deduplicate[T any](ctx context.Context, group *flightGroup, operation, id string, options Options, fn func(ctx context.Context) (T, error), clone func(T) T) (T, error) {
+	if group == nil || options.SkipDeduplication {
+		return fn(ctx)
+	}
+	key, err := flightKey(operation, id, options)
+	if err != nil {
+		return fn(ctx)
+	}
+
+	value, err, shared := group.do(ctx, key, func(ctx context.Context) (any, error) {
+		return fn(ctx)
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	result := value.(T)
+	if shared && clone != nil {
+		result = clone(result)
+	}
+	return result, nil
+}
+
+// flightKey identifies a request by operation, id, query and headers. The query
+// is canonical since it is encoded sorted, as the keys of the mongo query.
+func flightKey(operation, id string, options Options) (string, error) {
+	query := url.Values{}
+	if err := convertFilter(query, types.Filter(options.Filter)); err != nil {
+		return "", err
+	}
+
+	headers := &strings.Builder{}
+	if err := options.Headers.Write(headers); err != nil {
+		return "", err
+	}
+
+	return strings.Join([]string{operation, id, query.Encode(), headers.String()}, "\x00"), nil
+}
+
+// cloneJSON deep copies the value with a JSON round-trip, as the cache does, since
+// the results are decoded from JSON. The value is returned as it is if it can not
+// be encoded.
+func cloneJSON[T any](value T) T {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var cloned T
+	if err := json.Unmarshal(data, &cloned); err != nil {
+		return value
+	}
+	return cloned
+}
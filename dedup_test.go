@@ -0,0 +1,316 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gock "github.com/mia-platform/go-crud-service-client/testhelper/gock"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlightGroup(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("share the call in flight", func(t *testing.T) {
+		group := newFlightGroup()
+		release := make(chan struct{})
+		var calls int32
+
+		fn := func(context.Context) (any, error) {
+			atomic.AddInt32(&calls, 1)
+			<-release
+			return 42, nil
+		}
+
+		var wg sync.WaitGroup
+		results := make([]any, 5)
+		shared := make([]bool, 5)
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i], _, shared[i] = group.do(ctx, "key", fn)
+			}(i)
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		require.Equal(t, int32(1), calls)
+		require.Equal(t, []any{42, 42, 42, 42, 42}, results)
+		require.Equal(t, []bool{true, true, true, true, true}, shared)
+
+		_, _, isShared := group.do(ctx, "key", func(context.Context) (any, error) { return 1, nil })
+		require.False(t, isShared)
+	})
+
+	t.Run("stop waiting when the context is done", func(t *testing.T) {
+		group := newFlightGroup()
+		release := make(chan struct{})
+		defer close(release)
+
+		started := make(chan struct{})
+		go group.do(ctx, "key", func(context.Context) (any, error) {
+			close(started)
+			<-release
+			return nil, nil
+		})
+		<-started
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err, _ := group.do(ctx, "key", func(context.Context) (any, error) { return nil, nil })
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("the first caller cancelling does not fail the others", func(t *testing.T) {
+		group := newFlightGroup()
+		release := make(chan struct{})
+		started := make(chan struct{})
+
+		firstCtx, cancelFirst := context.WithCancel(context.Background())
+		firstDone := make(chan error)
+		go func() {
+			_, err, _ := group.do(firstCtx, "key", func(ctx context.Context) (any, error) {
+				close(started)
+				select {
+				case <-release:
+					return 42, nil
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			})
+			firstDone <- err
+		}()
+		<-started
+
+		secondDone := make(chan any)
+		go func() {
+			value, err, _ := group.do(ctx, "key", func(context.Context) (any, error) { return nil, nil })
+			require.NoError(t, err)
+			secondDone <- value
+		}()
+		require.Eventually(t, func() bool {
+			group.mu.Lock()
+			defer group.mu.Unlock()
+			return group.calls["key"] != nil && group.calls["key"].waiters == 2
+		}, time.Second, time.Millisecond)
+
+		cancelFirst()
+		require.ErrorIs(t, <-firstDone, context.Canceled)
+
+		close(release)
+		require.Equal(t, 42, <-secondDone)
+	})
+
+	t.Run("cancel the call when all the callers returned", func(t *testing.T) {
+		group := newFlightGroup()
+		cancelled := make(chan struct{})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			_, err, _ := group.do(ctx, "key", func(ctx context.Context) (any, error) {
+				<-ctx.Done()
+				close(cancelled)
+				return nil, ctx.Err()
+			})
+			done <- err
+		}()
+		require.Eventually(t, func() bool {
+			group.mu.Lock()
+			defer group.mu.Unlock()
+			return group.calls["key"] != nil
+		}, time.Second, time.Millisecond)
+
+		cancel()
+		require.ErrorIs(t, <-done, context.Canceled)
+		<-cancelled
+
+		value, err, _ := group.do(context.Background(), "key", func(context.Context) (any, error) { return 1, nil })
+		require.NoError(t, err)
+		require.Equal(t, 1, value)
+	})
+
+	t.Run("propagate panics and forget the call", func(t *testing.T) {
+		group := newFlightGroup()
+
+		require.PanicsWithValue(t, "boom", func() {
+			group.do(ctx, "key", func(context.Context) (any, error) { panic("boom") })
+		})
+
+		value, err, _ := group.do(ctx, "key", func(context.Context) (any, error) { return 1, nil })
+		require.NoError(t, err)
+		require.Equal(t, 1, value)
+	})
+}
+
+func TestFlightKey(t *testing.T) {
+	key := func(operation string, options Options) string {
+		k, err := flightKey(operation, "", options)
+		require.NoError(t, err)
+		return k
+	}
+
+	query := map[string]any{"a": 1, "b": map[string]any{"$gt": 2, "$lt": 4}}
+	sameQuery := map[string]any{"b": map[string]any{"$lt": 4, "$gt": 2}, "a": 1}
+	require.Equal(t,
+		key("count", Options{Filter: Filter{MongoQuery: query, Fields: map[string]string{"x": "1", "y": "2"}}}),
+		key("count", Options{Filter: Filter{MongoQuery: sameQuery, Fields: map[string]string{"y": "2", "x": "1"}}}),
+	)
+	require.NotEqual(t, key("count", Options{}), key("list", Options{}))
+	require.NotEqual(t,
+		key("count", Options{Headers: http.Header{"Authorization": []string{"a"}}}),
+		key("count", Options{Headers: http.Header{"Authorization": []string{"b"}}}),
+	)
+}
+
+func TestCloneJSON(t *testing.T) {
+	type resource struct {
+		Tags   []string          `json:"tags"`
+		Labels map[string]string `json:"labels"`
+		Parent *resource         `json:"parent,omitempty"`
+	}
+
+	original := []resource{{
+		Tags:   []string{"a"},
+		Labels: map[string]string{"k": "v"},
+		Parent: &resource{Tags: []string{"p"}},
+	}}
+	cloned := cloneJSON(original)
+	require.Equal(t, original, cloned)
+
+	cloned[0].Tags[0] = "changed"
+	cloned[0].Labels["k"] = "changed"
+	cloned[0].Parent.Tags[0] = "changed"
+	require.Equal(t, []resource{{
+		Tags:   []string{"a"},
+		Labels: map[string]string{"k": "v"},
+		Parent: &resource{Tags: []string{"p"}},
+	}}, original)
+
+	require.Nil(t, cloneJSON[*resource](nil))
+	require.Nil(t, cloneJSON[[]resource](nil))
+}
+
+func TestDeduplicateReads(t *testing.T) {
+	ctx := context.Background()
+
+	crudClient, err := NewClient[TestResource](ClientOptions{
+		BaseURL:          baseURL,
+		DeduplicateReads: true,
+	})
+	require.NoError(t, err)
+	client := crudClient.(Client[TestResource])
+
+	t.Run("identical concurrent calls share the request", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			Reply(200).
+			Delay(100 * time.Millisecond).
+			JSON([]TestResource{{ID: "1"}, {ID: "2"}})
+
+		var wg sync.WaitGroup
+		results := make([][]TestResource, 5)
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				resources, err := client.List(ctx, Options{Filter: Filter{Limit: 2}})
+				require.NoError(t, err)
+				results[i] = resources
+			}(i)
+		}
+		wg.Wait()
+
+		for _, resources := range results {
+			require.Equal(t, []TestResource{{ID: "1"}, {ID: "2"}}, resources)
+		}
+		results[0][0].ID = "changed"
+		require.Equal(t, "1", results[1][0].ID)
+	})
+
+	t.Run("a cancelled caller does not fail the others", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "count").
+			Reply(200).
+			Delay(100 * time.Millisecond).
+			JSON("7")
+
+		cancelledCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := client.Count(cancelledCtx, Options{})
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+		}()
+		go func() {
+			defer wg.Done()
+			time.Sleep(5 * time.Millisecond)
+			count, err := client.Count(ctx, Options{})
+			require.NoError(t, err)
+			require.Equal(t, 7, count)
+		}()
+		wg.Wait()
+	})
+
+	t.Run("skip deduplication per call", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "count").
+			Times(2).
+			Reply(200).
+			Delay(50 * time.Millisecond).
+			JSON("3")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				count, err := client.Count(ctx, Options{SkipDeduplication: true})
+				require.NoError(t, err)
+				require.Equal(t, 3, count)
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("share the errors", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "my-id").
+			Reply(404).
+			Delay(100*time.Millisecond).
+			AddHeader("Content-Type", "application/json").
+			BodyString(`{"message":"not found"}`)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := client.GetByID(ctx, "my-id", Options{})
+				require.ErrorIs(t, err, ErrResponse)
+			}()
+		}
+		wg.Wait()
+	})
+}
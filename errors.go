@@ -29,6 +29,25 @@ var (
 	ErrCreateRequest = fmt.Errorf("fails to create requests")
 
 	ErrResponse = fmt.Errorf("crud error")
+	ErrNotFound = fmt.Errorf("resource not found")
+
+	ErrInvalidState           = fmt.Errorf("invalid state")
+	ErrInvalidStateTransition = fmt.Errorf("invalid state transition")
+
+	ErrFilterConflict = fmt.Errorf("filters conflict")
+	ErrInvalidFilter  = fmt.Errorf("invalid filter")
+	ErrQuerySyntax    = fmt.Errorf("query syntax error")
+	ErrQueryPolicy    = fmt.Errorf("query policy violation")
+
+	ErrUnsupportedFilterVersion = fmt.Errorf("unsupported filter version")
+	ErrInvalidObjectID          = fmt.Errorf("invalid ObjectId")
+	ErrInvalidGeoPoint          = fmt.Errorf("invalid GeoPoint")
+	ErrUnsupportedOperator      = fmt.Errorf("unsupported operator")
+	ErrInvalidTemplate          = fmt.Errorf("invalid query template")
+	ErrTemplateBinding          = fmt.Errorf("query template binding failed")
+	ErrInvalidCursor            = fmt.Errorf("invalid cursor")
+	ErrCheckpoint               = fmt.Errorf("checkpoint error")
+	ErrWatch                    = fmt.Errorf("watch error")
 )
 
 type HTTPError struct {
@@ -55,6 +74,11 @@ func (e *HTTPError) Unwrap() error {
 	return e.Err
 }
 
+// Is reports that a 404 response is ErrNotFound.
+func (e *HTTPError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
 type CrudErrorResponse struct {
 	Message    string `json:"message,omitempty"`
 	StatusCode int    `json:"statusCode,omitempty"`
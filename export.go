@@ -0,0 +1,159 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/davidebianchi/go-jsonclient"
+)
+
+// ExportProgress reports how much of an export was delivered. It is returned also
+// when the export fails, to know where the stream was interrupted.
+type ExportProgress struct {
+	// Documents is the number of documents delivered to the caller
+	Documents int
+	// Bytes is the number of bytes read from the response
+	Bytes int64
+}
+
+// ExportStream calls /export endpoint of crud-service like Export, but it decodes the
+// resources one at a time while the response is read, calling fn for each of them.
+// The response is read only when fn returns, so a slow fn slows down the stream.
+// If fn returns an error, the export is stopped and the error is returned.
+func (c Client[Resource]) ExportStream(ctx context.Context, options Options, fn func(resource Resource) error) (ExportProgress, error) {
+	if err := c.checkQueryPolicy(options, false); err != nil {
+		return ExportProgress{}, err
+	}
+
+	req, err := c.client.NewRequestWithContext(ctx, http.MethodGet, "export", nil)
+	if err != nil {
+		return ExportProgress{}, fmt.Errorf("%w: %s", ErrCreateRequest, err)
+	}
+
+	if err := options.setOptionsInRequest(req); err != nil {
+		return ExportProgress{}, err
+	}
+
+	resp, err := c.doStream(req)
+	if err != nil {
+		return ExportProgress{}, err
+	}
+	defer resp.Body.Close()
+
+	body := &countingReader{reader: resp.Body}
+	progress := ExportProgress{}
+
+	decoder := json.NewDecoder(body)
+	for {
+		resource := new(Resource)
+		if err := decoder.Decode(resource); err != nil {
+			progress.Bytes = body.count
+			if err == io.EOF {
+				return progress, nil
+			}
+			return progress, err
+		}
+		if err := fn(*resource); err != nil {
+			progress.Bytes = body.count
+			return progress, err
+		}
+		progress.Documents++
+	}
+}
+
+// ExportFormat is the format of the exported resources, sent as Accept header
+// to the /export endpoint of crud-service.
+type ExportFormat string
+
+const (
+	// ExportFormatNDJSON exports the resources as newline delimited JSON.
+	ExportFormatNDJSON ExportFormat = "application/x-ndjson"
+	// ExportFormatCSV exports the resources as CSV.
+	ExportFormatCSV ExportFormat = "text/csv"
+	// ExportFormatExcel exports the resources as an XLSX spreadsheet.
+	ExportFormatExcel ExportFormat = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// ExportTo calls /export endpoint of crud-service asking for the given format, and
+// copies the response body to w as it is read, without decoding it.
+// If format is empty, ExportFormatNDJSON is used.
+// It returns the number of bytes written to w, also when the copy fails.
+func (c Client[Resource]) ExportTo(ctx context.Context, w io.Writer, format ExportFormat, options Options) (int64, error) {
+	if err := c.checkQueryPolicy(options, false); err != nil {
+		return 0, err
+	}
+
+	req, err := c.client.NewRequestWithContext(ctx, http.MethodGet, "export", nil)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrCreateRequest, err)
+	}
+
+	if err := options.setOptionsInRequest(req); err != nil {
+		return 0, err
+	}
+	if format == "" {
+		format = ExportFormatNDJSON
+	}
+	req.Header.Set("Accept", string(format))
+
+	resp, err := c.doStream(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	return io.Copy(w, resp.Body)
+}
+
+// doStream executes the request and returns the response without reading its body,
+// which must be closed by the caller. Errors are handled like the other requests.
+func (c Client[Resource]) doStream(req *http.Request) (*http.Response, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctxErr := req.Context().Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		raw, _ := io.ReadAll(resp.Body)
+		return nil, responseError(&jsonclient.HTTPError{
+			Response:   resp,
+			StatusCode: resp.StatusCode,
+			Err:        jsonclient.ErrHTTP,
+			Raw:        raw,
+		})
+	}
+	return resp, nil
+}
+
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	return n, err
+}
@@ -0,0 +1,78 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.23
+
+package crud
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	gock "github.com/mia-platform/go-crud-service-client/testhelper/gock"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportAll(t *testing.T) {
+	ctx := context.Background()
+	client := getClient(t)
+
+	t.Run("iterate exported resources", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "export").
+			Reply(200).
+			BodyString(`{"_id":"1"}` + "\n" + `{"_id":"2"}`)
+
+		ids := []string{}
+		for resource, err := range ExportAll[TestResource](ctx, client, Options{}) {
+			require.NoError(t, err)
+			ids = append(ids, resource.ID)
+		}
+		require.Equal(t, []string{"1", "2"}, ids)
+	})
+
+	t.Run("break the iteration", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "export").
+			Reply(200).
+			BodyString(`{"_id":"1"}` + "\n" + `{"_id":"2"}`)
+
+		ids := []string{}
+		for resource, err := range ExportAll[TestResource](ctx, client, Options{}) {
+			require.NoError(t, err)
+			ids = append(ids, resource.ID)
+			break
+		}
+		require.Equal(t, []string{"1"}, ids)
+	})
+
+	t.Run("yield the error", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "export").
+			Reply(200).
+			BodyString(`{"_id":"1"}` + "\n" + `{"_id"`)
+
+		ids := []string{}
+		var lastErr error
+		for resource, err := range ExportAll[TestResource](ctx, client, Options{}) {
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			ids = append(ids, resource.ID)
+		}
+		require.Equal(t, []string{"1"}, ids)
+		require.Error(t, lastErr)
+	})
+}
@@ -0,0 +1,181 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/mia-platform/go-crud-service-client/testhelper"
+	gock "github.com/mia-platform/go-crud-service-client/testhelper/gock"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportStream(t *testing.T) {
+	ctx := context.Background()
+	client := getClient(t)
+
+	response := []TestResource{
+		{Field: "v-1", IntField: 1, ID: "my-id-1"},
+		{Field: "v-2", IntField: 2, ID: "my-id-2"},
+		{Field: "v-3", IntField: 3, ID: "my-id-3"},
+	}
+	responseBody := testhelper.ParseResponseToNdjson[TestResource](t, response)
+
+	t.Run("stream resources", func(t *testing.T) {
+		filter := Filter{
+			MongoQuery: map[string]any{"field": map[string]any{"$in": []string{"v-1", "v-2"}}},
+			Projection: []string{"field"},
+		}
+
+		gock.NewGockScope(t, baseURL, http.MethodGet, "export").
+			AddMatcher(gock.CrudQueryMatcher(t, gock.Filter(filter))).
+			MatchHeader("foo", "bar").
+			Reply(200).
+			AddHeader("Content-Type", "application/x-ndjson").
+			BodyString(responseBody)
+
+		h := http.Header{}
+		h.Set("foo", "bar")
+
+		resources := []TestResource{}
+		progress, err := client.ExportStream(ctx, Options{Filter: filter, Headers: h}, func(resource TestResource) error {
+			resources = append(resources, resource)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, response, resources)
+		require.Equal(t, ExportProgress{Documents: 3, Bytes: int64(len(responseBody))}, progress)
+	})
+
+	t.Run("stop when callback fails", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "export").
+			Reply(200).
+			BodyString(responseBody)
+
+		expectedErr := errors.New("stop")
+		progress, err := client.ExportStream(ctx, Options{}, func(resource TestResource) error {
+			if resource.ID == "my-id-2" {
+				return expectedErr
+			}
+			return nil
+		})
+		require.ErrorIs(t, err, expectedErr)
+		require.Equal(t, 1, progress.Documents)
+	})
+
+	t.Run("return progress when the stream breaks", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "export").
+			Reply(200).
+			BodyString(`{"_id":"my-id-1"}` + "\n" + `{"_id":"my-id-2"}` + "\n" + `{"_id":"my-`)
+
+		ids := []string{}
+		progress, err := client.ExportStream(ctx, Options{}, func(resource TestResource) error {
+			ids = append(ids, resource.ID)
+			return nil
+		})
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		require.Equal(t, []string{"my-id-1", "my-id-2"}, ids)
+		require.Equal(t, 2, progress.Documents)
+		require.Equal(t, int64(47), progress.Bytes)
+	})
+
+	t.Run("throws with crud errors", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "export").
+			Reply(500).
+			AddHeader("Content-Type", "application/json").
+			BodyString(`{"message":"error message"}`)
+
+		progress, err := client.ExportStream(ctx, Options{}, func(resource TestResource) error { return nil })
+		require.ErrorIs(t, err, ErrResponse)
+		require.EqualError(t, err, "error message")
+
+		httpErr := &HTTPError{}
+		require.ErrorAs(t, err, &httpErr)
+		require.Equal(t, 500, httpErr.StatusCode)
+		require.Equal(t, ExportProgress{}, progress)
+	})
+
+	t.Run("throws with cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := client.ExportStream(ctx, Options{}, func(resource TestResource) error { return nil })
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestExportTo(t *testing.T) {
+	ctx := context.Background()
+	client := getClient(t)
+
+	csvBody := "_id,field\nmy-id-1,v-1\nmy-id-2,v-2\n"
+
+	t.Run("copy the response in the requested format", func(t *testing.T) {
+		filter := Filter{
+			Fields:     map[string]string{"field": "v-1"},
+			Projection: []string{"field"},
+		}
+
+		gock.NewGockScope(t, baseURL, http.MethodGet, "export").
+			AddMatcher(gock.CrudQueryMatcher(t, gock.Filter(filter))).
+			MatchHeader("Accept", "text/csv").
+			MatchHeader("foo", "bar").
+			Reply(200).
+			AddHeader("Content-Type", "text/csv").
+			BodyString(csvBody)
+
+		h := http.Header{}
+		h.Set("foo", "bar")
+
+		buf := &bytes.Buffer{}
+		n, err := client.ExportTo(ctx, buf, ExportFormatCSV, Options{Filter: filter, Headers: h})
+		require.NoError(t, err)
+		require.Equal(t, int64(len(csvBody)), n)
+		require.Equal(t, csvBody, buf.String())
+	})
+
+	t.Run("default to ndjson", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "export").
+			MatchHeader("Accept", "application/x-ndjson").
+			Reply(200).
+			BodyString(`{"_id":"my-id-1"}`)
+
+		buf := &bytes.Buffer{}
+		_, err := client.ExportTo(ctx, buf, "", Options{})
+		require.NoError(t, err)
+		require.Equal(t, `{"_id":"my-id-1"}`, buf.String())
+	})
+
+	t.Run("throws with crud errors", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "export").
+			Reply(406).
+			AddHeader("Content-Type", "application/json").
+			BodyString(`{"message":"unsupported format"}`)
+
+		buf := &bytes.Buffer{}
+		n, err := client.ExportTo(ctx, buf, ExportFormatExcel, Options{})
+		require.ErrorIs(t, err, ErrResponse)
+		require.EqualError(t, err, "unsupported format")
+		require.Zero(t, n)
+		require.Empty(t, buf.String())
+	})
+}
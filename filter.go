@@ -0,0 +1,229 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FilterConflictError is returned when the filters to merge set different values
+// for the same option.
+type FilterConflictError struct {
+	// Conflicts contains the name of the conflicting options, e.g. `limit` or `fields.name`
+	Conflicts []string
+}
+
+func (e *FilterConflictError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrFilterConflict, strings.Join(e.Conflicts, ", "))
+}
+
+func (e *FilterConflictError) Unwrap() error {
+	return ErrFilterConflict
+}
+
+// AndFilters combines the filters so that a document must match all of them.
+// Fields are merged together, while the MongoQuery are merged under `$and`.
+// Limit, Skip, Sort, Projection and State must be the same in all the filters
+// where they are set, and a field in Fields can not have different values.
+func AndFilters(filters ...Filter) (Filter, error) {
+	merged, conflicts := mergeFilterOptions(filters)
+
+	queries := []map[string]any{}
+	for _, filter := range filters {
+		for _, field := range sortedKeys(filter.Fields) {
+			value := filter.Fields[field]
+			if merged.Fields == nil {
+				merged.Fields = map[string]string{}
+			}
+			if current, ok := merged.Fields[field]; ok && current != value {
+				conflicts = appendConflict(conflicts, "fields."+field)
+				continue
+			}
+			merged.Fields[field] = value
+		}
+		queries = append(queries, filter.MongoQuery)
+	}
+
+	if len(conflicts) != 0 {
+		return Filter{}, &FilterConflictError{Conflicts: conflicts}
+	}
+
+	merged.MongoQuery = andMongoQuery(queries...)
+	return merged, nil
+}
+
+// OrFilters combines the filters so that a document must match at least one of them.
+// The MongoQuery are merged under `$or`. Fields must be the same in all the filters,
+// since they are not typed and can not be moved in the MongoQuery: use typed
+// conditions in the MongoQuery for the fields that differ.
+// Limit, Skip, Sort, Projection and State must be the same in all the filters
+// where they are set.
+func OrFilters(filters ...Filter) (Filter, error) {
+	merged, conflicts := mergeFilterOptions(filters)
+	if len(filters) != 0 {
+		merged.Fields = commonFields(filters)
+		for _, filter := range filters {
+			for _, field := range sortedKeys(filter.Fields) {
+				if _, ok := merged.Fields[field]; !ok {
+					conflicts = appendConflict(conflicts, "fields."+field)
+				}
+			}
+		}
+	}
+	if len(conflicts) != 0 {
+		return Filter{}, &FilterConflictError{Conflicts: conflicts}
+	}
+	if len(filters) == 0 {
+		return merged, nil
+	}
+
+	branches := []any{}
+	for _, filter := range filters {
+		if len(filter.MongoQuery) == 0 {
+			// a filter without conditions matches all the documents
+			return merged, nil
+		}
+		branches = append(branches, filter.MongoQuery)
+	}
+
+	if len(branches) == 1 {
+		merged.MongoQuery = branches[0].(map[string]any)
+	} else {
+		merged.MongoQuery = map[string]any{"$or": branches}
+	}
+	return merged, nil
+}
+
+func mergeFilterOptions(filters []Filter) (Filter, []string) {
+	merged := Filter{}
+	conflicts := []string{}
+
+	for _, filter := range filters {
+		if filter.Limit != 0 {
+			if merged.Limit != 0 && merged.Limit != filter.Limit {
+				conflicts = appendConflict(conflicts, "limit")
+			}
+			merged.Limit = filter.Limit
+		}
+		if filter.Skip != 0 {
+			if merged.Skip != 0 && merged.Skip != filter.Skip {
+				conflicts = appendConflict(conflicts, "skip")
+			}
+			merged.Skip = filter.Skip
+		}
+		if filter.Sort != "" {
+			if merged.Sort != "" && merged.Sort != filter.Sort {
+				conflicts = appendConflict(conflicts, "sort")
+			}
+			merged.Sort = filter.Sort
+		}
+		if filter.Projection != nil {
+			if merged.Projection != nil && strings.Join(merged.Projection, ",") != strings.Join(filter.Projection, ",") {
+				conflicts = appendConflict(conflicts, "projection")
+			}
+			merged.Projection = filter.Projection
+		}
+		if len(filter.State) != 0 {
+			if len(merged.State) != 0 && !sameStates(merged.State, filter.State) {
+				conflicts = appendConflict(conflicts, "state")
+			}
+			merged.State = filter.State
+		}
+	}
+
+	return merged, conflicts
+}
+
+func appendConflict(conflicts []string, name string) []string {
+	for _, conflict := range conflicts {
+		if conflict == name {
+			return conflicts
+		}
+	}
+	return append(conflicts, name)
+}
+
+func sameStates(a, b []State) bool {
+	setA, setB := stateSet(a), stateSet(b)
+	if len(setA) != len(setB) {
+		return false
+	}
+	for state := range setA {
+		if !setB[state] {
+			return false
+		}
+	}
+	return true
+}
+
+func stateSet(states []State) map[State]bool {
+	set := map[State]bool{}
+	for _, state := range states {
+		set[state] = true
+	}
+	return set
+}
+
+func commonFields(filters []Filter) map[string]string {
+	var common map[string]string
+	for field, value := range filters[0].Fields {
+		shared := true
+		for _, filter := range filters[1:] {
+			if other, ok := filter.Fields[field]; !ok || other != value {
+				shared = false
+				break
+			}
+		}
+		if shared {
+			if common == nil {
+				common = map[string]string{}
+			}
+			common[field] = value
+		}
+	}
+	return common
+}
+
+// andMongoQuery combines the non empty queries under `$and`. A single query is
+// returned as is, and nil is returned if there is no query.
+func andMongoQuery(queries ...map[string]any) map[string]any {
+	conditions := []any{}
+	for _, query := range queries {
+		if len(query) != 0 {
+			conditions = append(conditions, query)
+		}
+	}
+
+	switch len(conditions) {
+	case 0:
+		return nil
+	case 1:
+		return conditions[0].(map[string]any)
+	default:
+		return map[string]any{"$and": conditions}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
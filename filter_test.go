@@ -0,0 +1,188 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAndFilters(t *testing.T) {
+	t.Run("without filters", func(t *testing.T) {
+		filter, err := AndFilters()
+		require.NoError(t, err)
+		require.Equal(t, Filter{}, filter)
+	})
+
+	t.Run("with a single filter", func(t *testing.T) {
+		expected := Filter{
+			Fields:     map[string]string{"tenantId": "t-1"},
+			MongoQuery: map[string]any{"age": map[string]any{"$gt": 18}},
+			Limit:      5,
+		}
+
+		filter, err := AndFilters(expected)
+		require.NoError(t, err)
+		require.Equal(t, expected, filter)
+	})
+
+	t.Run("merge fields and mongo queries", func(t *testing.T) {
+		filter, err := AndFilters(
+			Filter{
+				Fields:     map[string]string{"tenantId": "t-1"},
+				MongoQuery: map[string]any{"owner": "user-1"},
+				Limit:      5,
+			},
+			Filter{
+				Fields:     map[string]string{"tenantId": "t-1", "status": "active"},
+				MongoQuery: map[string]any{"owner": "user-2"},
+				Sort:       "name",
+			},
+			Filter{
+				Projection: []string{"name"},
+				State:      []State{StatePublic, StateDraft},
+			},
+		)
+		require.NoError(t, err)
+		require.Equal(t, Filter{
+			Fields: map[string]string{"tenantId": "t-1", "status": "active"},
+			MongoQuery: map[string]any{
+				"$and": []any{
+					map[string]any{"owner": "user-1"},
+					map[string]any{"owner": "user-2"},
+				},
+			},
+			Limit:      5,
+			Sort:       "name",
+			Projection: []string{"name"},
+			State:      []State{StatePublic, StateDraft},
+		}, filter)
+	})
+
+	t.Run("same options are not conflicts", func(t *testing.T) {
+		filter, err := AndFilters(
+			Filter{Limit: 5, Skip: 2, Projection: []string{"a"}, State: []State{StatePublic, StateDraft}},
+			Filter{Limit: 5, Skip: 2, Projection: []string{"a"}, State: []State{StateDraft, StatePublic}},
+		)
+		require.NoError(t, err)
+		require.Equal(t, Filter{Limit: 5, Skip: 2, Projection: []string{"a"}, State: []State{StateDraft, StatePublic}}, filter)
+	})
+
+	t.Run("report conflicts", func(t *testing.T) {
+		filter, err := AndFilters(
+			Filter{
+				Fields:     map[string]string{"tenantId": "t-1"},
+				Limit:      5,
+				Skip:       1,
+				Sort:       "name",
+				Projection: []string{"a"},
+				State:      []State{StatePublic},
+			},
+			Filter{
+				Fields:     map[string]string{"tenantId": "t-2"},
+				Limit:      10,
+				Skip:       2,
+				Sort:       "-name",
+				Projection: []string{"b"},
+				State:      []State{StateDraft},
+			},
+		)
+		require.ErrorIs(t, err, ErrFilterConflict)
+		require.EqualError(t, err, "filters conflict: limit, skip, sort, projection, state, fields.tenantId")
+
+		conflictErr := &FilterConflictError{}
+		require.ErrorAs(t, err, &conflictErr)
+		require.Equal(t, []string{"limit", "skip", "sort", "projection", "state", "fields.tenantId"}, conflictErr.Conflicts)
+		require.Equal(t, Filter{}, filter)
+	})
+}
+
+func TestOrFilters(t *testing.T) {
+	t.Run("without filters", func(t *testing.T) {
+		filter, err := OrFilters()
+		require.NoError(t, err)
+		require.Equal(t, Filter{}, filter)
+	})
+
+	t.Run("merge mongo queries", func(t *testing.T) {
+		filter, err := OrFilters(
+			Filter{MongoQuery: map[string]any{"owner": "user-1"}, Limit: 5},
+			Filter{MongoQuery: map[string]any{"public": true}},
+		)
+		require.NoError(t, err)
+		require.Equal(t, Filter{
+			MongoQuery: map[string]any{
+				"$or": []any{
+					map[string]any{"owner": "user-1"},
+					map[string]any{"public": true},
+				},
+			},
+			Limit: 5,
+		}, filter)
+	})
+
+	t.Run("keep common fields", func(t *testing.T) {
+		filter, err := OrFilters(
+			Filter{
+				Fields:     map[string]string{"tenantId": "t-1"},
+				MongoQuery: map[string]any{"age": map[string]any{"$gt": 18}},
+			},
+			Filter{
+				Fields:     map[string]string{"tenantId": "t-1"},
+				MongoQuery: map[string]any{"status": "public"},
+			},
+		)
+		require.NoError(t, err)
+		require.Equal(t, Filter{
+			Fields: map[string]string{"tenantId": "t-1"},
+			MongoQuery: map[string]any{
+				"$or": []any{
+					map[string]any{"age": map[string]any{"$gt": 18}},
+					map[string]any{"status": "public"},
+				},
+			},
+		}, filter)
+	})
+
+	t.Run("report fields not shared by all the filters", func(t *testing.T) {
+		_, err := OrFilters(
+			Filter{Fields: map[string]string{"tenantId": "t-1", "owner": "user-1"}},
+			Filter{Fields: map[string]string{"tenantId": "t-1", "age": "18"}},
+			Filter{Fields: map[string]string{"tenantId": "t-2"}},
+		)
+		require.ErrorIs(t, err, ErrFilterConflict)
+		require.EqualError(t, err, "filters conflict: fields.owner, fields.tenantId, fields.age")
+	})
+
+	t.Run("filter without conditions matches everything", func(t *testing.T) {
+		filter, err := OrFilters(
+			Filter{Fields: map[string]string{"tenantId": "t-1"}, MongoQuery: map[string]any{"owner": "user-1"}},
+			Filter{Fields: map[string]string{"tenantId": "t-1"}},
+		)
+		require.NoError(t, err)
+		require.Equal(t, Filter{Fields: map[string]string{"tenantId": "t-1"}}, filter)
+	})
+
+	t.Run("report conflicts", func(t *testing.T) {
+		_, err := OrFilters(
+			Filter{Sort: "name"},
+			Filter{Sort: "-name"},
+		)
+		require.ErrorIs(t, err, ErrFilterConflict)
+		require.EqualError(t, err, "filters conflict: sort")
+	})
+}
@@ -0,0 +1,154 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/mia-platform/go-crud-service-client/internal/types"
+)
+
+// FilterSchemaVersion is the version of the format used to serialize a Filter
+const FilterSchemaVersion = 1
+
+// filterDocument is the serialized representation of a Filter. Adding optional fields
+// is fine, any other change requires to increase FilterSchemaVersion.
+type filterDocument struct {
+	Version    int               `json:"version" yaml:"version"`
+	Fields     map[string]string `json:"fields,omitempty" yaml:"fields,omitempty"`
+	MongoQuery map[string]any    `json:"mongoQuery,omitempty" yaml:"mongoQuery,omitempty"`
+	Limit      int               `json:"limit,omitempty" yaml:"limit,omitempty"`
+	Skip       int               `json:"skip,omitempty" yaml:"skip,omitempty"`
+	Sort       string            `json:"sort,omitempty" yaml:"sort,omitempty"`
+	Projection []string          `json:"projection,omitempty" yaml:"projection,omitempty"`
+	State      []State           `json:"state,omitempty" yaml:"state,omitempty"`
+}
+
+// filterVersion is used to know if a serialized filter has a version. The filters
+// without version have the legacy format, which is the one of the query parameters
+// of crud-service for JSON (e.g. `_q` and `_l`) and the lowercase field names for YAML.
+type filterVersion struct {
+	Version *int `json:"version" yaml:"version"`
+}
+
+func (d filterDocument) toFilter() (Filter, error) {
+	if d.Version < 1 || d.Version > FilterSchemaVersion {
+		return Filter{}, fmt.Errorf("%w: %d", ErrUnsupportedFilterVersion, d.Version)
+	}
+	return Filter{
+		Fields:     d.Fields,
+		MongoQuery: d.MongoQuery,
+		Limit:      d.Limit,
+		Skip:       d.Skip,
+		Sort:       d.Sort,
+		Projection: d.Projection,
+		State:      d.State,
+	}, nil
+}
+
+func (filter Filter) toDocument() filterDocument {
+	return filterDocument{
+		Version:    FilterSchemaVersion,
+		Fields:     filter.Fields,
+		MongoQuery: filter.MongoQuery,
+		Limit:      filter.Limit,
+		Skip:       filter.Skip,
+		Sort:       filter.Sort,
+		Projection: filter.Projection,
+		State:      filter.State,
+	}
+}
+
+// MarshalJSON encodes the filter with its schema version, so that it can be
+// saved and loaded later with UnmarshalJSON.
+func (filter Filter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(filter.toDocument())
+}
+
+// UnmarshalJSON decodes a filter encoded by MarshalJSON, or a filter in the legacy
+// format if the version is missing. Numbers in the MongoQuery are decoded as
+// json.Number, to keep them as they are.
+func (filter *Filter) UnmarshalJSON(data []byte) error {
+	var version filterVersion
+	if err := json.Unmarshal(data, &version); err != nil {
+		return err
+	}
+	if version.Version == nil {
+		var legacy types.Filter
+		if err := decodeJSONNumbers(data, &legacy); err != nil {
+			return err
+		}
+		*filter = Filter(legacy)
+		return nil
+	}
+
+	var document filterDocument
+	if err := decodeJSONNumbers(data, &document); err != nil {
+		return err
+	}
+
+	decoded, err := document.toFilter()
+	if err != nil {
+		return err
+	}
+	*filter = decoded
+	return nil
+}
+
+// MarshalYAML encodes the filter with its schema version, so that it can be
+// saved and loaded later with UnmarshalYAML.
+func (filter Filter) MarshalYAML() (any, error) {
+	return filter.toDocument(), nil
+}
+
+// UnmarshalYAML decodes a filter encoded by MarshalYAML, or a filter in the legacy
+// format if the version is missing.
+func (filter *Filter) UnmarshalYAML(value *yaml.Node) error {
+	var version filterVersion
+	if err := value.Decode(&version); err != nil {
+		return err
+	}
+	if version.Version == nil {
+		var legacy types.Filter
+		if err := value.Decode(&legacy); err != nil {
+			return err
+		}
+		*filter = Filter(legacy)
+		return nil
+	}
+
+	var document filterDocument
+	if err := value.Decode(&document); err != nil {
+		return err
+	}
+
+	decoded, err := document.toFilter()
+	if err != nil {
+		return err
+	}
+	*filter = decoded
+	return nil
+}
+
+func decodeJSONNumbers(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
@@ -0,0 +1,234 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	gock "github.com/mia-platform/go-crud-service-client/testhelper/gock"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestFilterJSON(t *testing.T) {
+	filter := Filter{
+		Fields: map[string]string{"tenantId": "t-1"},
+		MongoQuery: map[string]any{
+			"age":   map[string]any{"$gt": 18},
+			"score": map[string]any{"$lte": 12345678901234567},
+		},
+		Limit:      10,
+		Skip:       20,
+		Sort:       "-age",
+		Projection: []string{"name", "age"},
+		State:      []State{StatePublic, StateDraft},
+	}
+	expectedJSON := `{
+		"version": 1,
+		"fields": {"tenantId": "t-1"},
+		"mongoQuery": {"age": {"$gt": 18}, "score": {"$lte": 12345678901234567}},
+		"limit": 10,
+		"skip": 20,
+		"sort": "-age",
+		"projection": ["name", "age"],
+		"state": ["PUBLIC", "DRAFT"]
+	}`
+
+	t.Run("marshal", func(t *testing.T) {
+		data, err := json.Marshal(filter)
+		require.NoError(t, err)
+		require.JSONEq(t, expectedJSON, string(data))
+	})
+
+	t.Run("unmarshal keeps numbers", func(t *testing.T) {
+		var decoded Filter
+		require.NoError(t, json.Unmarshal([]byte(expectedJSON), &decoded))
+		require.Equal(t, Filter{
+			Fields: map[string]string{"tenantId": "t-1"},
+			MongoQuery: map[string]any{
+				"age":   map[string]any{"$gt": json.Number("18")},
+				"score": map[string]any{"$lte": json.Number("12345678901234567")},
+			},
+			Limit:      10,
+			Skip:       20,
+			Sort:       "-age",
+			Projection: []string{"name", "age"},
+			State:      []State{StatePublic, StateDraft},
+		}, decoded)
+
+		data, err := json.Marshal(decoded)
+		require.NoError(t, err)
+		require.JSONEq(t, expectedJSON, string(data))
+	})
+
+	t.Run("empty filter", func(t *testing.T) {
+		data, err := json.Marshal(Filter{})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"version":1}`, string(data))
+
+		var decoded Filter
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		require.Equal(t, Filter{}, decoded)
+	})
+
+	t.Run("unsupported version", func(t *testing.T) {
+		var decoded Filter
+		err := json.Unmarshal([]byte(`{"version":2,"limit":5}`), &decoded)
+		require.ErrorIs(t, err, ErrUnsupportedFilterVersion)
+		require.EqualError(t, err, "unsupported filter version: 2")
+
+		err = json.Unmarshal([]byte(`{"version":0,"limit":5}`), &decoded)
+		require.EqualError(t, err, "unsupported filter version: 0")
+	})
+
+	t.Run("unmarshal legacy format without version", func(t *testing.T) {
+		var decoded Filter
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"_q": {"age": {"$gt": 18}},
+			"_l": 10,
+			"_p": ["name", "age"],
+			"_sk": 20,
+			"_s": "-age",
+			"_st": ["PUBLIC", "DRAFT"]
+		}`), &decoded))
+		require.Equal(t, Filter{
+			MongoQuery: map[string]any{"age": map[string]any{"$gt": json.Number("18")}},
+			Limit:      10,
+			Skip:       20,
+			Sort:       "-age",
+			Projection: []string{"name", "age"},
+			State:      []State{StatePublic, StateDraft},
+		}, decoded)
+
+		decoded = Filter{Limit: 3}
+		require.NoError(t, json.Unmarshal([]byte(`{}`), &decoded))
+		require.Equal(t, Filter{}, decoded)
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		var decoded Filter
+		require.Error(t, json.Unmarshal([]byte(`{"version":`), &decoded))
+	})
+
+	t.Run("saved filter can be used to list", func(t *testing.T) {
+		client := getClient(t)
+
+		var decoded Filter
+		require.NoError(t, json.Unmarshal([]byte(expectedJSON), &decoded))
+
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			AddMatcher(gock.CrudQueryMatcher(t, gock.Filter(filter))).
+			Reply(200).
+			JSON([]TestResource{})
+
+		resources, err := client.List(context.Background(), Options{Filter: decoded})
+		require.NoError(t, err)
+		require.Equal(t, []TestResource{}, resources)
+	})
+}
+
+func TestFilterYAML(t *testing.T) {
+	filter := Filter{
+		Fields: map[string]string{"tenantId": "t-1"},
+		MongoQuery: map[string]any{
+			"$or": []any{
+				map[string]any{"age": map[string]any{"$gt": 18}},
+				map[string]any{"vip": true},
+			},
+		},
+		Limit:      10,
+		Sort:       "-age",
+		Projection: []string{"name"},
+		State:      []State{StateTrash},
+	}
+	expectedYAML := `version: 1
+fields:
+    tenantId: t-1
+mongoQuery:
+    $or:
+        - age:
+            $gt: 18
+        - vip: true
+limit: 10
+sort: -age
+projection:
+    - name
+state:
+    - TRASH
+`
+
+	t.Run("marshal", func(t *testing.T) {
+		data, err := yaml.Marshal(filter)
+		require.NoError(t, err)
+		require.Equal(t, expectedYAML, string(data))
+	})
+
+	t.Run("unmarshal", func(t *testing.T) {
+		var decoded Filter
+		require.NoError(t, yaml.Unmarshal([]byte(expectedYAML), &decoded))
+		require.Equal(t, filter, decoded)
+	})
+
+	t.Run("unmarshal in a config struct", func(t *testing.T) {
+		var config struct {
+			Searches map[string]Filter `yaml:"searches"`
+		}
+		err := yaml.Unmarshal([]byte(`
+searches:
+  adults:
+    version: 1
+    mongoQuery:
+      age:
+        $gte: 18
+`), &config)
+		require.NoError(t, err)
+		require.Equal(t, map[string]Filter{
+			"adults": {MongoQuery: map[string]any{"age": map[string]any{"$gte": 18}}},
+		}, config.Searches)
+	})
+
+	t.Run("unsupported version", func(t *testing.T) {
+		var decoded Filter
+		err := yaml.Unmarshal([]byte("version: 3\n"), &decoded)
+		require.ErrorIs(t, err, ErrUnsupportedFilterVersion)
+	})
+
+	t.Run("unmarshal legacy format without version", func(t *testing.T) {
+		var decoded Filter
+		require.NoError(t, yaml.Unmarshal([]byte(`
+mongoquery:
+  age:
+    $gt: 18
+limit: 10
+sort: -age
+projection:
+  - name
+state:
+  - TRASH
+`), &decoded))
+		require.Equal(t, Filter{
+			MongoQuery: map[string]any{"age": map[string]any{"$gt": 18}},
+			Limit:      10,
+			Sort:       "-age",
+			Projection: []string{"name"},
+			State:      []State{StateTrash},
+		}, decoded)
+	})
+}
@@ -0,0 +1,63 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"errors"
+)
+
+// FindOne returns the first resource matching the filter, listing with limit 1.
+// If no resource matches, ErrNotFound is returned.
+func (c Client[Resource]) FindOne(ctx context.Context, options Options) (*Resource, error) {
+	options.Filter.Limit = 1
+
+	resources, err := c.List(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	if len(resources) == 0 {
+		return nil, ErrNotFound
+	}
+	return &resources[0], nil
+}
+
+// Exists reports whether a resource matches the filter. It lists with limit 1 and
+// projection on `_id`, which is cheaper than a count that scans all the matches.
+func (c Client[Resource]) Exists(ctx context.Context, options Options) (bool, error) {
+	options.Filter.Limit = 1
+	options.Filter.Projection = []string{"_id"}
+
+	resources, err := c.List(ctx, options)
+	if err != nil {
+		return false, err
+	}
+	return len(resources) > 0, nil
+}
+
+// ExistsByID reports whether the resource with the given id exists, also considering
+// the filter of the options. It gets the resource with projection on `_id`.
+func (c Client[Resource]) ExistsByID(ctx context.Context, id string, options Options) (bool, error) {
+	options.Filter.Projection = []string{"_id"}
+
+	if _, err := c.GetByID(ctx, id, options); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
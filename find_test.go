@@ -0,0 +1,146 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	gock "github.com/mia-platform/go-crud-service-client/testhelper/gock"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindOne(t *testing.T) {
+	ctx := context.Background()
+	client := getClient(t)
+
+	t.Run("find the first resource", func(t *testing.T) {
+		filter := Filter{
+			Fields: map[string]string{"field": "v"},
+			Sort:   "-field",
+			Limit:  1,
+		}
+
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			AddMatcher(gock.CrudQueryMatcher(t, gock.Filter(filter))).
+			Reply(200).
+			JSON([]TestResource{{ID: "my-id", Field: "v"}})
+
+		resource, err := client.FindOne(ctx, Options{Filter: Filter{
+			Fields: map[string]string{"field": "v"},
+			Sort:   "-field",
+			Limit:  10,
+		}})
+		require.NoError(t, err)
+		require.Equal(t, &TestResource{ID: "my-id", Field: "v"}, resource)
+	})
+
+	t.Run("throws not found", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			Reply(200).
+			JSON([]TestResource{})
+
+		resource, err := client.FindOne(ctx, Options{})
+		require.ErrorIs(t, err, ErrNotFound)
+		require.Nil(t, resource)
+	})
+
+	t.Run("throws with crud errors", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			Reply(500).
+			AddHeader("Content-Type", "application/json").
+			BodyString(`{"message":"error message"}`)
+
+		_, err := client.FindOne(ctx, Options{})
+		require.ErrorIs(t, err, ErrResponse)
+		require.NotErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestExists(t *testing.T) {
+	ctx := context.Background()
+	client := getClient(t)
+
+	t.Run("resource exists", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			AddMatcher(gock.CrudQueryMatcher(t, gock.Filter{
+				Fields:     map[string]string{"field": "v"},
+				Limit:      1,
+				Projection: []string{"_id"},
+			})).
+			Reply(200).
+			JSON([]TestResource{{ID: "my-id"}})
+
+		exists, err := client.Exists(ctx, Options{Filter: Filter{Fields: map[string]string{"field": "v"}}})
+		require.NoError(t, err)
+		require.True(t, exists)
+	})
+
+	t.Run("resource does not exist", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			Reply(200).
+			JSON([]TestResource{})
+
+		exists, err := client.Exists(ctx, Options{})
+		require.NoError(t, err)
+		require.False(t, exists)
+	})
+}
+
+func TestExistsByID(t *testing.T) {
+	ctx := context.Background()
+	client := getClient(t)
+
+	t.Run("resource exists", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "my-id").
+			AddMatcher(gock.CrudQueryMatcher(t, gock.Filter{Projection: []string{"_id"}})).
+			Reply(200).
+			JSON(TestResource{ID: "my-id"})
+
+		exists, err := client.ExistsByID(ctx, "my-id", Options{})
+		require.NoError(t, err)
+		require.True(t, exists)
+	})
+
+	t.Run("resource does not exist", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "my-id").
+			Reply(404).
+			AddHeader("Content-Type", "application/json").
+			BodyString(`{"message":"not found"}`)
+
+		exists, err := client.ExistsByID(ctx, "my-id", Options{})
+		require.NoError(t, err)
+		require.False(t, exists)
+	})
+
+	t.Run("throws with other crud errors", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "my-id").
+			Reply(500).
+			AddHeader("Content-Type", "application/json").
+			BodyString(`{"message":"error message"}`)
+
+		exists, err := client.ExistsByID(ctx, "my-id", Options{})
+		require.ErrorIs(t, err, ErrResponse)
+		require.False(t, exists)
+	})
+}
+
+func TestHTTPErrorIsNotFound(t *testing.T) {
+	require.ErrorIs(t, &HTTPError{StatusCode: http.StatusNotFound, Err: ErrResponse}, ErrNotFound)
+	require.NotErrorIs(t, &HTTPError{StatusCode: http.StatusBadRequest, Err: ErrResponse}, ErrNotFound)
+}
@@ -0,0 +1,170 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// earthRadiusMeters is the radius used by MongoDB to convert distances in radians
+const earthRadiusMeters = 6378100
+
+// GeoPoint is a point in the format of crud-service GeoPoint fields: `[longitude, latitude]`.
+type GeoPoint struct {
+	Longitude float64
+	Latitude  float64
+}
+
+func (p GeoPoint) coordinates() []float64 {
+	return []float64{p.Longitude, p.Latitude}
+}
+
+func (p GeoPoint) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.coordinates())
+}
+
+// UnmarshalJSON decodes the crud-service format `[longitude, latitude]`, and the GeoJSON
+// format `{"type": "Point", "coordinates": [longitude, latitude]}` saved in MongoDB.
+func (p *GeoPoint) UnmarshalJSON(data []byte) error {
+	var coordinates []float64
+	if err := json.Unmarshal(data, &coordinates); err != nil {
+		var geoJSON struct {
+			Type        string    `json:"type"`
+			Coordinates []float64 `json:"coordinates"`
+		}
+		if err := json.Unmarshal(data, &geoJSON); err != nil || geoJSON.Type != "Point" {
+			return fmt.Errorf("%w: %s", ErrInvalidGeoPoint, data)
+		}
+		coordinates = geoJSON.Coordinates
+	}
+	if len(coordinates) != 2 {
+		return fmt.Errorf("%w: %s", ErrInvalidGeoPoint, data)
+	}
+
+	p.Longitude = coordinates[0]
+	p.Latitude = coordinates[1]
+	return nil
+}
+
+// GeoGeometry is a GeoJSON geometry, used by GeoIntersects.
+type GeoGeometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// PointGeometry returns the GeoJSON Point of the point.
+func PointGeometry(point GeoPoint) GeoGeometry {
+	return GeoGeometry{Type: "Point", Coordinates: point.coordinates()}
+}
+
+// LineStringGeometry returns the GeoJSON LineString passing by the points.
+func LineStringGeometry(points ...GeoPoint) GeoGeometry {
+	return GeoGeometry{Type: "LineString", Coordinates: pointsCoordinates(points)}
+}
+
+// PolygonGeometry returns the GeoJSON Polygon with the ring as exterior ring.
+// The ring is closed if the last point is not equal to the first one.
+func PolygonGeometry(ring ...GeoPoint) GeoGeometry {
+	if len(ring) > 0 && ring[0] != ring[len(ring)-1] {
+		ring = append(ring[:len(ring):len(ring)], ring[0])
+	}
+	return GeoGeometry{Type: "Polygon", Coordinates: [][][]float64{pointsCoordinates(ring)}}
+}
+
+func (g GeoGeometry) toMap() map[string]any {
+	return map[string]any{"type": g.Type, "coordinates": g.Coordinates}
+}
+
+// NearSphere returns the condition to sort the documents by distance from the point,
+// in the format supported by crud-service. Distances are in meters, and a zero
+// maxDistance means no limit. E.g.
+//
+//	MongoQuery: map[string]any{"position": crud.NearSphere(point, 0, 1000)}
+func NearSphere(point GeoPoint, minDistance, maxDistance float64) map[string]any {
+	near := map[string]any{"from": point.coordinates()}
+	if minDistance > 0 {
+		near["minDistance"] = minDistance
+	}
+	if maxDistance > 0 {
+		near["maxDistance"] = maxDistance
+	}
+	return map[string]any{"$nearSphere": near}
+}
+
+// Near returns the `$near` condition to sort the documents by distance from the point.
+// Distances are in meters, and a zero maxDistance means no limit.
+func Near(point GeoPoint, minDistance, maxDistance float64) map[string]any {
+	near := map[string]any{"$geometry": PointGeometry(point).toMap()}
+	if minDistance > 0 {
+		near["$minDistance"] = minDistance
+	}
+	if maxDistance > 0 {
+		near["$maxDistance"] = maxDistance
+	}
+	return map[string]any{"$near": near}
+}
+
+// GeoWithinBox returns the condition to match the points inside the box, as a GeoJSON
+// Polygon so that it matches the GeoJSON points of crud-service GeoPoint fields.
+// Since the edges of a GeoJSON Polygon are geodesics, the box is not exactly
+// bounded by the latitudes of its corners.
+func GeoWithinBox(bottomLeft, upperRight GeoPoint) map[string]any {
+	return GeoWithinPolygon(
+		bottomLeft,
+		GeoPoint{Longitude: upperRight.Longitude, Latitude: bottomLeft.Latitude},
+		upperRight,
+		GeoPoint{Longitude: bottomLeft.Longitude, Latitude: upperRight.Latitude},
+	)
+}
+
+// GeoWithinPolygon returns the condition to match the points inside the polygon, as a
+// GeoJSON Polygon so that it matches the GeoJSON points of crud-service GeoPoint fields.
+// The ring is closed if the last point is not equal to the first one.
+func GeoWithinPolygon(points ...GeoPoint) map[string]any {
+	return map[string]any{
+		"$geoWithin": map[string]any{
+			"$geometry": PolygonGeometry(points...).toMap(),
+		},
+	}
+}
+
+// GeoWithinCenterSphere returns the condition to match the points inside the circle
+// on the Earth surface with the specified center and radius in meters.
+func GeoWithinCenterSphere(center GeoPoint, radius float64) map[string]any {
+	return map[string]any{
+		"$geoWithin": map[string]any{
+			"$centerSphere": []any{center.coordinates(), radius / earthRadiusMeters},
+		},
+	}
+}
+
+// GeoIntersects returns the condition to match the geometries intersecting the geometry.
+func GeoIntersects(geometry GeoGeometry) map[string]any {
+	return map[string]any{
+		"$geoIntersects": map[string]any{
+			"$geometry": geometry.toMap(),
+		},
+	}
+}
+
+func pointsCoordinates(points []GeoPoint) [][]float64 {
+	coordinates := make([][]float64, 0, len(points))
+	for _, point := range points {
+		coordinates = append(coordinates, point.coordinates())
+	}
+	return coordinates
+}
@@ -0,0 +1,151 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	gock "github.com/mia-platform/go-crud-service-client/testhelper/gock"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeoPoint(t *testing.T) {
+	point := GeoPoint{Longitude: 9.18, Latitude: 45.46}
+
+	t.Run("marshal", func(t *testing.T) {
+		data, err := json.Marshal(point)
+		require.NoError(t, err)
+		require.Equal(t, `[9.18,45.46]`, string(data))
+	})
+
+	t.Run("unmarshal crud-service format", func(t *testing.T) {
+		var decoded GeoPoint
+		require.NoError(t, json.Unmarshal([]byte(`[9.18,45.46]`), &decoded))
+		require.Equal(t, point, decoded)
+	})
+
+	t.Run("unmarshal GeoJSON format", func(t *testing.T) {
+		var decoded GeoPoint
+		require.NoError(t, json.Unmarshal([]byte(`{"type":"Point","coordinates":[9.18,45.46]}`), &decoded))
+		require.Equal(t, point, decoded)
+	})
+
+	t.Run("unmarshal invalid point", func(t *testing.T) {
+		for _, invalid := range []string{`[1]`, `{"type":"LineString","coordinates":[1,2]}`, `"abc"`} {
+			var decoded GeoPoint
+			err := json.Unmarshal([]byte(invalid), &decoded)
+			require.ErrorIs(t, err, ErrInvalidGeoPoint)
+		}
+	})
+}
+
+func TestGeoQueries(t *testing.T) {
+	a := GeoPoint{Longitude: 1, Latitude: 2}
+	b := GeoPoint{Longitude: 3, Latitude: 4}
+	c := GeoPoint{Longitude: 5, Latitude: 2}
+
+	tests := []struct {
+		name     string
+		query    map[string]any
+		expected string
+	}{
+		{
+			name:     "near sphere",
+			query:    NearSphere(a, 10, 1000),
+			expected: `{"$nearSphere":{"from":[1,2],"minDistance":10,"maxDistance":1000}}`,
+		},
+		{
+			name:     "near sphere without distances",
+			query:    NearSphere(a, 0, 0),
+			expected: `{"$nearSphere":{"from":[1,2]}}`,
+		},
+		{
+			name:     "near",
+			query:    Near(a, 0, 500),
+			expected: `{"$near":{"$geometry":{"type":"Point","coordinates":[1,2]},"$maxDistance":500}}`,
+		},
+		{
+			name:     "within box",
+			query:    GeoWithinBox(a, b),
+			expected: `{"$geoWithin":{"$geometry":{"type":"Polygon","coordinates":[[[1,2],[3,2],[3,4],[1,4],[1,2]]]}}}`,
+		},
+		{
+			name:     "within polygon",
+			query:    GeoWithinPolygon(a, b, c),
+			expected: `{"$geoWithin":{"$geometry":{"type":"Polygon","coordinates":[[[1,2],[3,4],[5,2],[1,2]]]}}}`,
+		},
+		{
+			name:     "within center sphere",
+			query:    GeoWithinCenterSphere(a, earthRadiusMeters/2),
+			expected: `{"$geoWithin":{"$centerSphere":[[1,2],0.5]}}`,
+		},
+		{
+			name:     "intersects polygon",
+			query:    GeoIntersects(PolygonGeometry(a, b, c)),
+			expected: `{"$geoIntersects":{"$geometry":{"type":"Polygon","coordinates":[[[1,2],[3,4],[5,2],[1,2]]]}}}`,
+		},
+		{
+			name:     "intersects line",
+			query:    GeoIntersects(LineStringGeometry(a, b)),
+			expected: `{"$geoIntersects":{"$geometry":{"type":"LineString","coordinates":[[1,2],[3,4]]}}}`,
+		},
+		{
+			name:     "intersects point",
+			query:    GeoIntersects(PointGeometry(b)),
+			expected: `{"$geoIntersects":{"$geometry":{"type":"Point","coordinates":[3,4]}}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := json.Marshal(test.query)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, string(data))
+		})
+	}
+
+	t.Run("closed polygon is not closed again", func(t *testing.T) {
+		ring := []GeoPoint{a, b, c, a}
+		require.Equal(t, GeoGeometry{
+			Type:        "Polygon",
+			Coordinates: [][][]float64{{{1, 2}, {3, 4}, {5, 2}, {1, 2}}},
+		}, PolygonGeometry(ring...))
+		require.Len(t, ring, 4)
+	})
+}
+
+func TestListWithGeoQuery(t *testing.T) {
+	client := getClient(t)
+
+	filter := Filter{
+		MongoQuery: map[string]any{
+			"position": NearSphere(GeoPoint{Longitude: 9.18, Latitude: 45.46}, 0, 1000),
+		},
+	}
+
+	gock.NewGockScope(t, baseURL, http.MethodGet, "").
+		AddMatcher(gock.CrudQueryMatcher(t, gock.Filter(filter))).
+		Reply(200).
+		JSON([]TestResource{})
+
+	resources, err := client.List(context.Background(), Options{Filter: filter})
+	require.NoError(t, err)
+	require.Equal(t, []TestResource{}, resources)
+}
@@ -1,12 +1,22 @@
 package crud
 
-import "context"
+import (
+	"context"
+	"io"
+)
 
 type CrudClient[Resource any] interface {
 	GetByID(ctx context.Context, id string, options Options) (*Resource, error)
+	GetByIDs(ctx context.Context, ids []string, options Options) ([]Resource, []string, error)
 	List(ctx context.Context, options Options) ([]Resource, error)
 	Count(ctx context.Context, options Options) (int, error)
+	ListPage(ctx context.Context, options Options) (Page[Resource], error)
+	FindOne(ctx context.Context, options Options) (*Resource, error)
+	Exists(ctx context.Context, options Options) (bool, error)
+	ExistsByID(ctx context.Context, id string, options Options) (bool, error)
 	Export(ctx context.Context, options Options) ([]Resource, error)
+	ExportStream(ctx context.Context, options Options, fn func(resource Resource) error) (ExportProgress, error)
+	ExportTo(ctx context.Context, w io.Writer, format ExportFormat, options Options) (int64, error)
 	PatchById(ctx context.Context, id string, body PatchBody, options Options) (*Resource, error)
 	PatchMany(ctx context.Context, body PatchBody, options Options) (int, error)
 	PatchBulk(ctx context.Context, body PatchBulkBody, options Options) (int, error)
@@ -15,4 +25,6 @@ type CrudClient[Resource any] interface {
 	DeleteById(ctx context.Context, id string, options Options) error
 	DeleteMany(ctx context.Context, options Options) (int, error)
 	UpsertOne(ctx context.Context, body UpsertBody, options Options) (*Resource, error)
+	ChangeStateByID(ctx context.Context, id string, stateTo State, options Options) (int, error)
+	ChangeStateMany(ctx context.Context, body ChangeStateBody, options Options) (int, error)
 }
@@ -15,6 +15,9 @@
 
 package types
 
+// State is the state of a document in crud-service
+type State string
+
 type Filter struct {
 	Fields     map[string]string `json:"-"`
 	MongoQuery map[string]any    `json:"_q,omitempty"`
@@ -22,4 +25,5 @@ type Filter struct {
 	Projection []string          `json:"_p,omitempty"`
 	Skip       int               `json:"_sk,omitempty"`
 	Sort       string            `json:"_s,omitempty"`
+	State      []State           `json:"_st,omitempty"`
 }
@@ -0,0 +1,218 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultLoaderWait is the time a Loader waits for other ids before getting a batch.
+	DefaultLoaderWait = time.Millisecond
+	// DefaultLoaderMaxBatch is the max number of ids in a batch of a Loader.
+	DefaultLoaderMaxBatch = DefaultPageSize
+)
+
+// LoaderOptions configures a Loader.
+type LoaderOptions struct {
+	// Wait is the time to wait for other ids after the first id of a batch.
+	// Default is DefaultLoaderWait.
+	Wait time.Duration
+	// MaxBatch is the number of ids that triggers the batch without waiting.
+	// Default is DefaultLoaderMaxBatch.
+	MaxBatch int
+}
+
+// Loader coalesces the resources loaded by id from many goroutines: the ids loaded
+// within a short window are got with a single GetByIDs, and the results are cached by id.
+// A Loader is meant to live as long as a request, for example stored in the context
+// of the request with WithLoader, so that the cache is not stale.
+type Loader[Resource any] struct {
+	client   CrudClient[Resource]
+	options  Options
+	wait     time.Duration
+	maxBatch int
+
+	mu    sync.Mutex
+	cache map[string]*loaderResult[Resource]
+	batch *loaderBatch[Resource]
+}
+
+type loaderResult[Resource any] struct {
+	done     chan struct{}
+	resource *Resource
+	err      error
+}
+
+type loaderBatch[Resource any] struct {
+	ctx     context.Context
+	ids     []string
+	results map[string]*loaderResult[Resource]
+	timer   *time.Timer
+}
+
+// NewLoader creates a Loader that gets the resources with the given options.
+func NewLoader[Resource any](client CrudClient[Resource], options Options, loaderOptions LoaderOptions) *Loader[Resource] {
+	wait := loaderOptions.Wait
+	if wait <= 0 {
+		wait = DefaultLoaderWait
+	}
+	maxBatch := loaderOptions.MaxBatch
+	if maxBatch <= 0 {
+		maxBatch = DefaultLoaderMaxBatch
+	}
+	return &Loader[Resource]{
+		client:   client,
+		options:  options,
+		wait:     wait,
+		maxBatch: maxBatch,
+		cache:    map[string]*loaderResult[Resource]{},
+	}
+}
+
+// Load returns the resource with the given id. If the resource does not exist,
+// an error wrapping ErrNotFound is returned. The batch is not cancelled if ctx is
+// done, since other goroutines may be waiting for it: only this Load returns.
+func (l *Loader[Resource]) Load(ctx context.Context, id string) (*Resource, error) {
+	key := normalizeID(id)
+
+	l.mu.Lock()
+	result, ok := l.cache[key]
+	if !ok {
+		result = &loaderResult[Resource]{done: make(chan struct{})}
+		l.cache[key] = result
+		l.enqueue(ctx, key, result)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-result.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+	resource := *result.resource
+	return &resource, nil
+}
+
+// Clear removes the id from the cache, so that the next Load gets it again.
+func (l *Loader[Resource]) Clear(id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.cache, normalizeID(id))
+}
+
+// ClearAll empties the cache.
+func (l *Loader[Resource]) ClearAll() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cache = map[string]*loaderResult[Resource]{}
+}
+
+// enqueue adds the id to the current batch, which is got after the wait or when
+// it is full. It must be called with the lock held.
+func (l *Loader[Resource]) enqueue(ctx context.Context, key string, result *loaderResult[Resource]) {
+	if l.batch == nil {
+		// the batch must not be cancelled with the context of the first Load
+		batch := &loaderBatch[Resource]{
+			ctx:     detachedContext{Context: ctx},
+			results: map[string]*loaderResult[Resource]{},
+		}
+		batch.timer = time.AfterFunc(l.wait, func() {
+			l.mu.Lock()
+			if l.batch == batch {
+				l.batch = nil
+			}
+			l.mu.Unlock()
+			l.dispatch(batch)
+		})
+		l.batch = batch
+	}
+
+	l.batch.ids = append(l.batch.ids, key)
+	l.batch.results[key] = result
+
+	if len(l.batch.ids) >= l.maxBatch {
+		batch := l.batch
+		l.batch = nil
+		if batch.timer.Stop() {
+			go l.dispatch(batch)
+		}
+	}
+}
+
+func (l *Loader[Resource]) dispatch(batch *loaderBatch[Resource]) {
+	resources, _, err := l.client.GetByIDs(batch.ctx, batch.ids, l.options)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err != nil {
+		for key, result := range batch.results {
+			result.err = err
+			// errors are not cached, so that the next Load retries
+			if l.cache[key] == result {
+				delete(l.cache, key)
+			}
+			close(result.done)
+		}
+		return
+	}
+
+	for _, resource := range resources {
+		resource := resource
+		id, err := resourceID(resource)
+		if err != nil {
+			continue
+		}
+		if result, ok := batch.results[resourceIDKey(id)]; ok {
+			result.resource = &resource
+		}
+	}
+	for key, result := range batch.results {
+		if result.resource == nil {
+			result.err = fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+		close(result.done)
+	}
+}
+
+type loaderContextKey[Resource any] struct{}
+
+// WithLoader returns a copy of ctx that carries the loader.
+func WithLoader[Resource any](ctx context.Context, loader *Loader[Resource]) context.Context {
+	return context.WithValue(ctx, loaderContextKey[Resource]{}, loader)
+}
+
+// LoaderFromContext returns the loader of Resource carried by ctx, if any.
+func LoaderFromContext[Resource any](ctx context.Context) (*Loader[Resource], bool) {
+	loader, ok := ctx.Value(loaderContextKey[Resource]{}).(*Loader[Resource])
+	return loader, ok
+}
+
+// detachedContext keeps the values of the parent context, but it is never done.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
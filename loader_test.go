@@ -0,0 +1,166 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type getByIDsClient struct {
+	CrudClient[TestResource]
+
+	mu      sync.Mutex
+	batches [][]string
+	err     error
+}
+
+func (c *getByIDsClient) GetByIDs(_ context.Context, ids []string, _ Options) ([]TestResource, []string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	batch := append([]string{}, ids...)
+	sort.Strings(batch)
+	c.batches = append(c.batches, batch)
+	if c.err != nil {
+		return nil, nil, c.err
+	}
+
+	resources := []TestResource{}
+	notFound := []string{}
+	for _, id := range ids {
+		if id == "missing" {
+			notFound = append(notFound, id)
+			continue
+		}
+		resources = append(resources, TestResource{ID: id, Field: "field-" + id})
+	}
+	return resources, notFound, nil
+}
+
+func TestLoader(t *testing.T) {
+	ctx := context.Background()
+
+	loadAll := func(loader *Loader[TestResource], ids ...string) ([]*TestResource, []error) {
+		resources := make([]*TestResource, len(ids))
+		errs := make([]error, len(ids))
+
+		var wg sync.WaitGroup
+		for i, id := range ids {
+			wg.Add(1)
+			go func(i int, id string) {
+				defer wg.Done()
+				resources[i], errs[i] = loader.Load(ctx, id)
+			}(i, id)
+		}
+		wg.Wait()
+		return resources, errs
+	}
+
+	t.Run("coalesce loads in a batch", func(t *testing.T) {
+		client := &getByIDsClient{}
+		loader := NewLoader[TestResource](client, Options{}, LoaderOptions{Wait: 20 * time.Millisecond})
+
+		resources, errs := loadAll(loader, "1", "2", "1", "missing")
+		require.Equal(t, [][]string{{"1", "2", "missing"}}, client.batches)
+		require.Equal(t, &TestResource{ID: "1", Field: "field-1"}, resources[0])
+		require.Equal(t, &TestResource{ID: "2", Field: "field-2"}, resources[1])
+		require.Equal(t, &TestResource{ID: "1", Field: "field-1"}, resources[2])
+		require.NoError(t, errs[0])
+		require.ErrorIs(t, errs[3], ErrNotFound)
+	})
+
+	t.Run("cache loaded ids", func(t *testing.T) {
+		client := &getByIDsClient{}
+		loader := NewLoader[TestResource](client, Options{}, LoaderOptions{})
+
+		_, err := loader.Load(ctx, "1")
+		require.NoError(t, err)
+		_, err = loader.Load(ctx, "1")
+		require.NoError(t, err)
+		_, err = loader.Load(ctx, "missing")
+		require.ErrorIs(t, err, ErrNotFound)
+		_, err = loader.Load(ctx, "missing")
+		require.ErrorIs(t, err, ErrNotFound)
+		require.Equal(t, [][]string{{"1"}, {"missing"}}, client.batches)
+
+		loader.Clear("1")
+		_, err = loader.Load(ctx, "1")
+		require.NoError(t, err)
+		require.Len(t, client.batches, 3)
+
+		loader.ClearAll()
+		_, err = loader.Load(ctx, "missing")
+		require.ErrorIs(t, err, ErrNotFound)
+		require.Len(t, client.batches, 4)
+	})
+
+	t.Run("split batches by max batch", func(t *testing.T) {
+		client := &getByIDsClient{}
+		loader := NewLoader[TestResource](client, Options{}, LoaderOptions{Wait: time.Hour, MaxBatch: 2})
+
+		_, errs := loadAll(loader, "1", "2", "3", "4")
+		for _, err := range errs {
+			require.NoError(t, err)
+		}
+		require.Len(t, client.batches, 2)
+	})
+
+	t.Run("do not cache errors", func(t *testing.T) {
+		expectedErr := errors.New("crud error")
+		client := &getByIDsClient{err: expectedErr}
+		loader := NewLoader[TestResource](client, Options{}, LoaderOptions{})
+
+		_, err := loader.Load(ctx, "1")
+		require.ErrorIs(t, err, expectedErr)
+
+		client.err = nil
+		resource, err := loader.Load(ctx, "1")
+		require.NoError(t, err)
+		require.Equal(t, "1", resource.ID)
+	})
+
+	t.Run("return when the context is done", func(t *testing.T) {
+		client := &getByIDsClient{}
+		loader := NewLoader[TestResource](client, Options{}, LoaderOptions{Wait: time.Hour})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := loader.Load(ctx, "1")
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("store the loader in the context", func(t *testing.T) {
+		loader := NewLoader[TestResource](&getByIDsClient{}, Options{}, LoaderOptions{})
+
+		_, ok := LoaderFromContext[TestResource](ctx)
+		require.False(t, ok)
+
+		loaderCtx := WithLoader(ctx, loader)
+		actual, ok := LoaderFromContext[TestResource](loaderCtx)
+		require.True(t, ok)
+		require.Same(t, loader, actual)
+
+		_, ok = LoaderFromContext[struct{}](loaderCtx)
+		require.False(t, ok)
+	})
+}
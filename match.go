@@ -0,0 +1,469 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Matches reports whether the document satisfies the filter, evaluating it as crud-service
+// and MongoDB would. The document could be a Resource or any value encodable in JSON.
+//
+// Fields are compared with the string representation of the document values, State is
+// checked against `__STATE__` (a document without it is considered PUBLIC, and an empty
+// State matches only PUBLIC documents as in crud-service), and the MongoQuery supports
+// the operators $eq, $ne, $gt, $gte, $lt, $lte, $in, $nin, $exists,
+// $regex, $options, $size, $all, $elemMatch, $not, $and, $or and $nor.
+// Limit, Skip, Sort and Projection are ignored.
+func Matches(filter Filter, document any) (bool, error) {
+	doc, err := normalizeJSON(document)
+	if err != nil {
+		return false, err
+	}
+
+	states := filter.State
+	if len(states) == 0 {
+		states = []State{StatePublic}
+	}
+	state := StatePublic
+	if docMap, ok := doc.(map[string]any); ok {
+		if value, ok := docMap["__STATE__"].(string); ok {
+			state = State(value)
+		}
+	}
+	if !stateSet(states)[state] {
+		return false, nil
+	}
+
+	for _, field := range sortedKeys(filter.Fields) {
+		if !matchField(lookupPath(doc, field), filter.Fields[field]) {
+			return false, nil
+		}
+	}
+
+	if filter.MongoQuery == nil {
+		return true, nil
+	}
+	query, err := normalizeJSON(encodeQueryValues(filter.MongoQuery))
+	if err != nil {
+		return false, err
+	}
+	queryMap, ok := query.(map[string]any)
+	if !ok {
+		return false, fmt.Errorf("%w: query is not an object", ErrInvalidFilter)
+	}
+	return matchQuery(doc, queryMap)
+}
+
+// normalizeJSON converts the value to the types produced by decoding JSON.
+func normalizeJSON(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var normalized any
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// mongoTruthy reports whether MongoDB considers the value true, as the operand of
+// $exists: false, 0 and null are false, everything else is true.
+func mongoTruthy(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	}
+	return true
+}
+
+func matchField(values []any, expected string) bool {
+	for _, value := range expandArrays(values) {
+		var actual string
+		switch v := value.(type) {
+		case string:
+			actual = v
+		case float64:
+			actual = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			actual = strconv.FormatBool(v)
+		default:
+			continue
+		}
+		if actual == expected {
+			return true
+		}
+	}
+	return false
+}
+
+func matchQuery(doc any, query map[string]any) (bool, error) {
+	for _, key := range sortedKeys(query) {
+		condition := query[key]
+
+		var matched bool
+		var err error
+		switch key {
+		case "$and", "$or", "$nor":
+			matched, err = matchLogical(doc, key, condition)
+		default:
+			if strings.HasPrefix(key, "$") {
+				return false, fmt.Errorf("%w: %s", ErrUnsupportedOperator, key)
+			}
+			matched, err = matchCondition(lookupPath(doc, key), condition)
+		}
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchLogical(doc any, operator string, condition any) (bool, error) {
+	conditions, ok := condition.([]any)
+	if !ok {
+		return false, fmt.Errorf("%w: %s requires an array", ErrInvalidFilter, operator)
+	}
+
+	for _, item := range conditions {
+		subQuery, ok := item.(map[string]any)
+		if !ok {
+			return false, fmt.Errorf("%w: %s requires an array of objects", ErrInvalidFilter, operator)
+		}
+		matched, err := matchQuery(doc, subQuery)
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case operator == "$and" && !matched:
+			return false, nil
+		case operator == "$or" && matched:
+			return true, nil
+		case operator == "$nor" && matched:
+			return false, nil
+		}
+	}
+	return operator != "$or", nil
+}
+
+// matchCondition evaluates the condition on the values found at the path of a field.
+func matchCondition(values []any, condition any) (bool, error) {
+	operators, ok := condition.(map[string]any)
+	if !ok || !hasOperators(operators) {
+		return matchEquals(values, condition), nil
+	}
+
+	options, _ := operators["$options"].(string)
+	for _, operator := range sortedKeys(operators) {
+		if operator == "$options" {
+			continue
+		}
+		matched, err := matchOperator(values, operator, operators[operator], options)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func hasOperators(condition map[string]any) bool {
+	for key := range condition {
+		if strings.HasPrefix(key, "$") {
+			return true
+		}
+	}
+	return false
+}
+
+func matchOperator(values []any, operator string, operand any, options string) (bool, error) {
+	switch operator {
+	case "$eq":
+		return matchEquals(values, operand), nil
+	case "$ne":
+		return !matchEquals(values, operand), nil
+	case "$gt", "$gte", "$lt", "$lte":
+		return matchCompare(values, operator, operand), nil
+	case "$in", "$nin":
+		candidates, ok := operand.([]any)
+		if !ok {
+			return false, fmt.Errorf("%w: %s requires an array", ErrInvalidFilter, operator)
+		}
+		found := false
+		for _, candidate := range candidates {
+			if matchEquals(values, candidate) {
+				found = true
+				break
+			}
+		}
+		return found == (operator == "$in"), nil
+	case "$exists":
+		return (len(values) != 0) == mongoTruthy(operand), nil
+	case "$regex":
+		return matchRegex(values, operand, options)
+	case "$size":
+		size, ok := operand.(float64)
+		if !ok {
+			return false, fmt.Errorf("%w: $size requires a number", ErrInvalidFilter)
+		}
+		for _, value := range values {
+			if array, ok := value.([]any); ok && float64(len(array)) == size {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "$all":
+		required, ok := operand.([]any)
+		if !ok {
+			return false, fmt.Errorf("%w: $all requires an array", ErrInvalidFilter)
+		}
+		for _, item := range required {
+			if !matchEquals(values, item) {
+				return false, nil
+			}
+		}
+		return len(required) != 0, nil
+	case "$elemMatch":
+		return matchElem(values, operand)
+	case "$not":
+		var matched bool
+		var err error
+		if condition, ok := operand.(map[string]any); ok {
+			matched, err = matchCondition(values, condition)
+		} else {
+			matched, err = matchRegex(values, operand, "")
+		}
+		return !matched, err
+	default:
+		return false, fmt.Errorf("%w: %s", ErrUnsupportedOperator, operator)
+	}
+}
+
+func matchEquals(values []any, expected any) bool {
+	if expected == nil && len(values) == 0 {
+		return true
+	}
+	for _, value := range values {
+		if reflect.DeepEqual(value, expected) {
+			return true
+		}
+		if array, ok := value.([]any); ok {
+			for _, item := range array {
+				if reflect.DeepEqual(item, expected) {
+					return true
+				}
+			}
+		}
+		if sameDate(value, expected) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchCompare(values []any, operator string, operand any) bool {
+	for _, value := range expandArrays(values) {
+		cmp, ok := compareValues(value, operand)
+		if !ok {
+			continue
+		}
+		switch {
+		case operator == "$gt" && cmp > 0,
+			operator == "$gte" && cmp >= 0,
+			operator == "$lt" && cmp < 0,
+			operator == "$lte" && cmp <= 0:
+			return true
+		}
+	}
+	return false
+}
+
+// compareValues compares values of the same type. Strings are compared as dates
+// if both of them are dates.
+func compareValues(a, b any) (int, bool) {
+	switch av := a.(type) {
+	case float64:
+		bv, ok := b.(float64)
+		if !ok {
+			return 0, false
+		}
+		return compareOrdered(av, bv), true
+	case string:
+		bv, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		at, aErr := time.Parse(time.RFC3339Nano, av)
+		bt, bErr := time.Parse(time.RFC3339Nano, bv)
+		if aErr == nil && bErr == nil {
+			return at.Compare(bt), true
+		}
+		return strings.Compare(av, bv), true
+	case bool:
+		bv, ok := b.(bool)
+		if !ok {
+			return 0, false
+		}
+		if av == bv {
+			return 0, true
+		}
+		if !av {
+			return -1, true
+		}
+		return 1, true
+	}
+	return 0, false
+}
+
+func compareOrdered(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func sameDate(a, b any) bool {
+	cmp, ok := compareValues(a, b)
+	_, isString := a.(string)
+	return ok && isString && cmp == 0
+}
+
+func matchRegex(values []any, pattern any, options string) (bool, error) {
+	expression, ok := pattern.(string)
+	if !ok {
+		return false, fmt.Errorf("%w: $regex requires a string", ErrInvalidFilter)
+	}
+	flags := ""
+	for _, option := range options {
+		if strings.ContainsRune("ims", option) {
+			flags += string(option)
+		}
+	}
+	if flags != "" {
+		expression = "(?" + flags + ")" + expression
+	}
+
+	re, err := regexp.Compile(expression)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s", ErrInvalidFilter, err)
+	}
+	for _, value := range expandArrays(values) {
+		if s, ok := value.(string); ok && re.MatchString(s) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func matchElem(values []any, operand any) (bool, error) {
+	query, ok := operand.(map[string]any)
+	if !ok {
+		return false, fmt.Errorf("%w: $elemMatch requires an object", ErrInvalidFilter)
+	}
+
+	for _, value := range values {
+		array, ok := value.([]any)
+		if !ok {
+			continue
+		}
+		for _, item := range array {
+			var matched bool
+			var err error
+			if hasOperators(query) && !hasLogicalOperators(query) {
+				matched, err = matchCondition([]any{item}, query)
+			} else {
+				matched, err = matchQuery(item, query)
+			}
+			if err != nil {
+				return false, err
+			}
+			if matched {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func hasLogicalOperators(query map[string]any) bool {
+	for key := range query {
+		if logicalOperators[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupPath returns the values found at the dotted path. When an intermediate
+// value is an array, the path is followed in each of its elements.
+func lookupPath(doc any, path string) []any {
+	values := []any{doc}
+	for _, key := range strings.Split(path, ".") {
+		next := []any{}
+		for _, value := range values {
+			next = append(next, lookupKey(value, key)...)
+		}
+		values = next
+	}
+	return values
+}
+
+func lookupKey(value any, key string) []any {
+	switch v := value.(type) {
+	case map[string]any:
+		if item, ok := v[key]; ok {
+			return []any{item}
+		}
+	case []any:
+		if index, err := strconv.Atoi(key); err == nil {
+			if index >= 0 && index < len(v) {
+				return []any{v[index]}
+			}
+			return nil
+		}
+		found := []any{}
+		for _, item := range v {
+			found = append(found, lookupKey(item, key)...)
+		}
+		return found
+	}
+	return nil
+}
+
+func expandArrays(values []any) []any {
+	expanded := []any{}
+	for _, value := range values {
+		if array, ok := value.([]any); ok {
+			expanded = append(expanded, array...)
+			continue
+		}
+		expanded = append(expanded, value)
+	}
+	return expanded
+}
@@ -0,0 +1,151 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatches(t *testing.T) {
+	type item struct {
+		Name  string `json:"name"`
+		Price int    `json:"price"`
+	}
+	type document struct {
+		Metadata
+		ID      string         `json:"_id"`
+		Name    string         `json:"name"`
+		Age     int            `json:"age"`
+		VIP     bool           `json:"vip"`
+		Tags    []string       `json:"tags"`
+		Items   []item         `json:"items"`
+		Address map[string]any `json:"address,omitempty"`
+	}
+
+	updatedAt := time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC)
+	doc := document{
+		Metadata: Metadata{UpdatedAt: &updatedAt, State: StatePublic},
+		ID:       "id-1",
+		Name:     "Alice",
+		Age:      30,
+		VIP:      true,
+		Tags:     []string{"a", "b"},
+		Items:    []item{{Name: "pen", Price: 2}, {Name: "book", Price: 15}},
+		Address:  map[string]any{"city": "Milan", "zip": nil},
+	}
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected bool
+	}{
+		{name: "empty filter", filter: Filter{}, expected: true},
+		{name: "fields", filter: Filter{Fields: map[string]string{"name": "Alice", "age": "30", "vip": "true"}}, expected: true},
+		{name: "fields in array", filter: Filter{Fields: map[string]string{"tags": "b"}}, expected: true},
+		{name: "fields not matching", filter: Filter{Fields: map[string]string{"age": "31"}}, expected: false},
+		{name: "state", filter: Filter{State: []State{StatePublic, StateDraft}}, expected: true},
+		{name: "state not matching", filter: Filter{State: []State{StateDraft}}, expected: false},
+		{name: "equality", filter: Filter{MongoQuery: map[string]any{"name": "Alice", "address.city": "Milan"}}, expected: true},
+		{name: "equality on array element", filter: Filter{MongoQuery: map[string]any{"tags": "a"}}, expected: true},
+		{name: "equality on whole array", filter: Filter{MongoQuery: map[string]any{"tags": []string{"a", "b"}}}, expected: true},
+		{name: "equality on embedded document", filter: Filter{MongoQuery: map[string]any{"items.0": map[string]any{"name": "pen", "price": 2}}}, expected: true},
+		{name: "null matches missing and null", filter: Filter{MongoQuery: map[string]any{"missing": nil, "address.zip": nil}}, expected: true},
+		{name: "$eq and $ne", filter: Filter{MongoQuery: map[string]any{"age": map[string]any{"$eq": 30, "$ne": 31}}}, expected: true},
+		{name: "$ne on array", filter: Filter{MongoQuery: map[string]any{"tags": map[string]any{"$ne": "a"}}}, expected: false},
+		{name: "range", filter: Filter{MongoQuery: map[string]any{"age": map[string]any{"$gt": 18, "$lte": 30}}}, expected: true},
+		{name: "range not matching", filter: Filter{MongoQuery: map[string]any{"age": map[string]any{"$lt": 30}}}, expected: false},
+		{name: "range on strings", filter: Filter{MongoQuery: map[string]any{"name": map[string]any{"$gte": "A", "$lt": "B"}}}, expected: true},
+		{name: "range on different types", filter: Filter{MongoQuery: map[string]any{"name": map[string]any{"$gt": 1}}}, expected: false},
+		{name: "range on dates", filter: Filter{MongoQuery: map[string]any{"updatedAt": map[string]any{"$gt": time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}}}, expected: true},
+		{name: "equality on dates", filter: Filter{MongoQuery: map[string]any{"updatedAt": updatedAt}}, expected: true},
+		{name: "range on array elements", filter: Filter{MongoQuery: map[string]any{"items.price": map[string]any{"$gt": 10}}}, expected: true},
+		{name: "$in", filter: Filter{MongoQuery: map[string]any{"name": map[string]any{"$in": []string{"Bob", "Alice"}}}}, expected: true},
+		{name: "$in on array", filter: Filter{MongoQuery: map[string]any{"tags": map[string]any{"$in": []string{"c", "b"}}}}, expected: true},
+		{name: "$nin", filter: Filter{MongoQuery: map[string]any{"name": map[string]any{"$nin": []string{"Bob", "Alice"}}}}, expected: false},
+		{name: "$exists", filter: Filter{MongoQuery: map[string]any{"name": map[string]any{"$exists": true}, "missing": map[string]any{"$exists": false}}}, expected: true},
+		{name: "$exists with numbers", filter: Filter{MongoQuery: map[string]any{"name": map[string]any{"$exists": 1}, "missing": map[string]any{"$exists": 0}}}, expected: true},
+		{name: "$exists with numbers not matching", filter: Filter{MongoQuery: map[string]any{"name": map[string]any{"$exists": 0}}}, expected: false},
+		{name: "$exists with null", filter: Filter{MongoQuery: map[string]any{"missing": map[string]any{"$exists": nil}}}, expected: true},
+		{name: "$regex", filter: Filter{MongoQuery: map[string]any{"name": map[string]any{"$regex": "^ali", "$options": "i"}}}, expected: true},
+		{name: "$regex not matching", filter: Filter{MongoQuery: map[string]any{"name": map[string]any{"$regex": "^ali"}}}, expected: false},
+		{name: "$not", filter: Filter{MongoQuery: map[string]any{"age": map[string]any{"$not": map[string]any{"$gt": 40}}}}, expected: true},
+		{name: "$size", filter: Filter{MongoQuery: map[string]any{"tags": map[string]any{"$size": 2}}}, expected: true},
+		{name: "$all", filter: Filter{MongoQuery: map[string]any{"tags": map[string]any{"$all": []string{"b", "a"}}}}, expected: true},
+		{name: "$all not matching", filter: Filter{MongoQuery: map[string]any{"tags": map[string]any{"$all": []string{"a", "c"}}}}, expected: false},
+		{name: "$elemMatch on documents", filter: Filter{MongoQuery: map[string]any{"items": map[string]any{"$elemMatch": map[string]any{"name": "book", "price": map[string]any{"$gt": 10}}}}}, expected: true},
+		{name: "$elemMatch not matching", filter: Filter{MongoQuery: map[string]any{"items": map[string]any{"$elemMatch": map[string]any{"name": "pen", "price": map[string]any{"$gt": 10}}}}}, expected: false},
+		{name: "$elemMatch on values", filter: Filter{MongoQuery: map[string]any{"tags": map[string]any{"$elemMatch": map[string]any{"$gte": "b"}}}}, expected: true},
+		{name: "$and", filter: Filter{MongoQuery: map[string]any{"$and": []any{map[string]any{"age": 30}, map[string]any{"vip": true}}}}, expected: true},
+		{name: "$or", filter: Filter{MongoQuery: map[string]any{"$or": []any{map[string]any{"age": 10}, map[string]any{"vip": true}}}}, expected: true},
+		{name: "$or not matching", filter: Filter{MongoQuery: map[string]any{"$or": []any{map[string]any{"age": 10}, map[string]any{"vip": false}}}}, expected: false},
+		{name: "$nor", filter: Filter{MongoQuery: map[string]any{"$nor": []any{map[string]any{"age": 10}, map[string]any{"vip": false}}}}, expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matched, err := Matches(test.filter, doc)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, matched)
+		})
+	}
+
+	t.Run("raw JSON document", func(t *testing.T) {
+		matched, err := Matches(Filter{MongoQuery: map[string]any{"a.b": map[string]any{"$gte": 2}}}, json.RawMessage(`{"a":{"b":2}}`))
+		require.NoError(t, err)
+		require.True(t, matched)
+	})
+
+	t.Run("document without state is public", func(t *testing.T) {
+		matched, err := Matches(Filter{State: []State{StatePublic}}, map[string]any{"a": 1})
+		require.NoError(t, err)
+		require.True(t, matched)
+	})
+
+	t.Run("empty state matches only public documents", func(t *testing.T) {
+		matched, err := Matches(Filter{}, map[string]any{"__STATE__": "DRAFT"})
+		require.NoError(t, err)
+		require.False(t, matched)
+
+		matched, err = Matches(Filter{}, map[string]any{"__STATE__": "PUBLIC"})
+		require.NoError(t, err)
+		require.True(t, matched)
+
+		matched, err = Matches(Filter{}, map[string]any{"a": 1})
+		require.NoError(t, err)
+		require.True(t, matched)
+	})
+
+	t.Run("unsupported operator", func(t *testing.T) {
+		_, err := Matches(Filter{MongoQuery: map[string]any{"$where": "true"}}, doc)
+		require.ErrorIs(t, err, ErrUnsupportedOperator)
+		require.EqualError(t, err, "unsupported operator: $where")
+
+		_, err = Matches(Filter{MongoQuery: map[string]any{"name": map[string]any{"$type": "string"}}}, doc)
+		require.EqualError(t, err, "unsupported operator: $type")
+	})
+
+	t.Run("invalid operand", func(t *testing.T) {
+		_, err := Matches(Filter{MongoQuery: map[string]any{"name": map[string]any{"$in": "Alice"}}}, doc)
+		require.ErrorIs(t, err, ErrInvalidFilter)
+
+		_, err = Matches(Filter{MongoQuery: map[string]any{"$or": map[string]any{}}}, doc)
+		require.ErrorIs(t, err, ErrInvalidFilter)
+	})
+}
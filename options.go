@@ -34,6 +34,12 @@ type Setter interface {
 type Options struct {
 	Filter  Filter
 	Headers http.Header
+	// SkipDeduplication sends the request even if an identical one is in flight,
+	// when the client deduplicates the reads.
+	SkipDeduplication bool
+	// SkipCache reads from crud-service even if the result is cached, when the
+	// client caches the reads. The fresh result is cached.
+	SkipCache bool
 }
 
 func (o Options) setOptionsInRequest(req *http.Request) error {
@@ -84,12 +90,16 @@ func convertFilter(query url.Values, filter types.Filter) error {
 		query.Set("_s", filter.Sort)
 	}
 
+	if len(filter.State) != 0 {
+		query.Set("_st", joinStates(filter.State))
+	}
+
 	return nil
 }
 
 func convertMongoQuery(query Setter, mongoQuery map[string]any) error {
 	if mongoQuery != nil {
-		queryBytes, err := json.Marshal(mongoQuery)
+		queryBytes, err := json.Marshal(encodeQueryValues(mongoQuery))
 		if err != nil {
 			return err
 		}
@@ -120,6 +120,13 @@ func TestAddCrudQueryToRequest(t *testing.T) {
 			},
 			expectedUnencodedQuery: `_sk=4`,
 		},
+		{
+			name: "with only state",
+			filter: types.Filter{
+				State: []types.State{"PUBLIC", "TRASH"},
+			},
+			expectedUnencodedQuery: `_st=PUBLIC,TRASH`,
+		},
 	}
 
 	for _, test := range tests {
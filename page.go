@@ -0,0 +1,102 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"sync"
+)
+
+// Page is a page of resources returned by ListPage, with the total number of
+// resources matching the filter.
+type Page[Resource any] struct {
+	Items []Resource
+	// Total is the number of resources matching the filter, ignoring limit and skip
+	Total int
+	// PageSize is the limit of the filter
+	PageSize int
+	// PageIndex is the index of the page, starting from 0, computed from skip and limit
+	PageIndex int
+	// HasNext reports whether there are resources after this page
+	HasNext bool
+	// HasPrevious reports whether there are resources before this page
+	HasPrevious bool
+}
+
+// ListPage calls List and Count concurrently with the same filter, to get a page of
+// resources and the total number of resources. Limit, skip, sort and projection
+// are not used by Count. If one of the calls fails, the other is cancelled.
+func (c Client[Resource]) ListPage(ctx context.Context, options Options) (Page[Resource], error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	countOptions := options
+	countOptions.Filter.Limit = 0
+	countOptions.Filter.Skip = 0
+	countOptions.Filter.Sort = ""
+	countOptions.Filter.Projection = nil
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		total    int
+	)
+	// the error of the call that failed first is returned, since the other
+	// call may fail only because it was cancelled.
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		count, err := c.Count(ctx, countOptions)
+		if err != nil {
+			fail(err)
+			return
+		}
+		total = count
+	}()
+
+	items, err := c.List(ctx, options)
+	if err != nil {
+		fail(err)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return Page[Resource]{}, firstErr
+	}
+	return newPage(items, total, options.Filter.Limit, options.Filter.Skip), nil
+}
+
+func newPage[Resource any](items []Resource, total, limit, skip int) Page[Resource] {
+	page := Page[Resource]{
+		Items:       items,
+		Total:       total,
+		PageSize:    limit,
+		HasNext:     skip+len(items) < total,
+		HasPrevious: skip > 0,
+	}
+	if limit > 0 {
+		page.PageIndex = skip / limit
+	}
+	return page
+}
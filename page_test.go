@@ -0,0 +1,117 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	gock "github.com/mia-platform/go-crud-service-client/testhelper/gock"
+
+	h2gock "github.com/h2non/gock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListPage(t *testing.T) {
+	ctx := context.Background()
+	client := getClient(t)
+
+	resources := []TestResource{
+		{Field: "v-3", ID: "my-id-3"},
+		{Field: "v-4", ID: "my-id-4"},
+	}
+
+	t.Run("list a page with the total", func(t *testing.T) {
+		filter := Filter{
+			Fields:     map[string]string{"field": "v"},
+			Limit:      2,
+			Skip:       2,
+			Sort:       "field",
+			Projection: []string{"field"},
+		}
+
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			AddMatcher(gock.CrudQueryMatcher(t, gock.Filter(filter))).
+			Reply(200).
+			JSON(resources)
+		gock.NewGockScope(t, baseURL, http.MethodGet, "count").
+			MatchParam("field", "^v$").
+			AddMatcher(func(req *http.Request, _ *h2gock.Request) (bool, error) {
+				query := req.URL.Query()
+				return !query.Has("_l") && !query.Has("_sk") && !query.Has("_s") && !query.Has("_p"), nil
+			}).
+			Reply(200).
+			JSON("5")
+
+		page, err := client.ListPage(ctx, Options{Filter: filter})
+		require.NoError(t, err)
+		require.Equal(t, Page[TestResource]{
+			Items:       resources,
+			Total:       5,
+			PageSize:    2,
+			PageIndex:   1,
+			HasNext:     true,
+			HasPrevious: true,
+		}, page)
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			Reply(200).
+			JSON(resources)
+		gock.NewGockScope(t, baseURL, http.MethodGet, "count").
+			Reply(200).
+			JSON("4")
+
+		page, err := client.ListPage(ctx, Options{Filter: Filter{Limit: 2, Skip: 2}})
+		require.NoError(t, err)
+		require.False(t, page.HasNext)
+		require.True(t, page.HasPrevious)
+	})
+
+	t.Run("throws if count fails", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			Reply(200).
+			JSON(resources)
+		gock.NewGockScope(t, baseURL, http.MethodGet, "count").
+			Reply(500).
+			AddHeader("Content-Type", "application/json").
+			BodyString(`{"message":"count error"}`)
+
+		_, err := client.ListPage(ctx, Options{Filter: Filter{Limit: 2}})
+		require.ErrorIs(t, err, ErrResponse)
+		require.EqualError(t, err, "count error")
+		// the list may be cancelled before its request
+		h2gock.Flush()
+	})
+
+	t.Run("throws if list fails", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			Reply(500).
+			AddHeader("Content-Type", "application/json").
+			BodyString(`{"message":"list error"}`)
+		gock.NewGockScope(t, baseURL, http.MethodGet, "count").
+			Reply(200).
+			JSON("4")
+
+		_, err := client.ListPage(ctx, Options{Filter: Filter{Limit: 2}})
+		require.ErrorIs(t, err, ErrResponse)
+		require.EqualError(t, err, "list error")
+		// the count may be cancelled before its request
+		h2gock.Flush()
+	})
+}
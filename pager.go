@@ -0,0 +1,104 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import "context"
+
+// DefaultPageSize is the page size used by the Pager if not specified. It is
+// the max page size accepted by default by crud-service.
+const DefaultPageSize = 200
+
+// Pager lists all the resources matching a filter, calling List one page at a time.
+// The Skip of the filter is used as starting offset, while the Limit is replaced
+// by the page size. A Pager must not be used concurrently.
+type Pager[Resource any] struct {
+	client   CrudClient[Resource]
+	options  Options
+	pageSize int
+
+	page int
+	done bool
+}
+
+// NewPager creates a Pager that lists pages of pageSize resources. If pageSize is not
+// positive, DefaultPageSize is used.
+func NewPager[Resource any](client CrudClient[Resource], options Options, pageSize int) *Pager[Resource] {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	return &Pager[Resource]{
+		client:   client,
+		options:  options,
+		pageSize: pageSize,
+	}
+}
+
+// Page returns the number of the last fetched page, starting from 1. It is 0 if
+// no page was fetched.
+func (p *Pager[Resource]) Page() int {
+	return p.page
+}
+
+// Done reports whether all the pages were fetched.
+func (p *Pager[Resource]) Done() bool {
+	return p.done
+}
+
+// NextPage fetches the next page. When a page shorter than the page size is
+// returned, the pager is done and next calls return an empty page.
+func (p *Pager[Resource]) NextPage(ctx context.Context) ([]Resource, error) {
+	if p.done {
+		return []Resource{}, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	options := p.options
+	options.Filter.Limit = p.pageSize
+	options.Filter.Skip = p.options.Filter.Skip + p.page*p.pageSize
+
+	resources, err := p.client.List(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	p.page++
+	if len(resources) < p.pageSize {
+		p.done = true
+	}
+	return resources, nil
+}
+
+// ForEach calls fn for each resource, fetching the pages when needed. It stops at
+// the first error returned by fn or by the client, or when ctx is cancelled.
+func (p *Pager[Resource]) ForEach(ctx context.Context, fn func(resource Resource) error) error {
+	for !p.done {
+		resources, err := p.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, resource := range resources {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(resource); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.23
+
+package crud
+
+import (
+	"context"
+	"errors"
+	"iter"
+)
+
+var errStopIteration = errors.New("stop iteration")
+
+// All returns an iterator over the resources, fetching the pages when needed.
+// If an error occurs, it is yielded with a zero Resource and the iteration stops.
+func (p *Pager[Resource]) All(ctx context.Context) iter.Seq2[Resource, error] {
+	return func(yield func(Resource, error) bool) {
+		err := p.ForEach(ctx, func(resource Resource) error {
+			if !yield(resource, nil) {
+				return errStopIteration
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errStopIteration) {
+			var zero Resource
+			yield(zero, err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.23
+
+package crud
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	gock "github.com/mia-platform/go-crud-service-client/testhelper/gock"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPagerAll(t *testing.T) {
+	ctx := context.Background()
+	client := getClient(t)
+
+	t.Run("iterate all the resources", func(t *testing.T) {
+		mockListPage(t, Filter{Limit: 2}, []TestResource{{ID: "1"}, {ID: "2"}})
+		mockListPage(t, Filter{Limit: 2, Skip: 2}, []TestResource{{ID: "3"}})
+
+		ids := []string{}
+		for resource, err := range NewPager[TestResource](client, Options{}, 2).All(ctx) {
+			require.NoError(t, err)
+			ids = append(ids, resource.ID)
+		}
+		require.Equal(t, []string{"1", "2", "3"}, ids)
+	})
+
+	t.Run("break the iteration", func(t *testing.T) {
+		mockListPage(t, Filter{Limit: 2}, []TestResource{{ID: "1"}, {ID: "2"}})
+
+		pager := NewPager[TestResource](client, Options{}, 2)
+		for resource, err := range pager.All(ctx) {
+			require.NoError(t, err)
+			require.Equal(t, "1", resource.ID)
+			break
+		}
+		require.Equal(t, 1, pager.Page())
+	})
+
+	t.Run("yield the error", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			Reply(500).
+			JSON(CrudErrorResponse{Message: "broken", StatusCode: 500})
+
+		errs := []error{}
+		for _, err := range NewPager[TestResource](client, Options{}, 2).All(ctx) {
+			errs = append(errs, err)
+		}
+		require.Len(t, errs, 1)
+		require.EqualError(t, errs[0], "broken")
+	})
+}
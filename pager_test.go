@@ -0,0 +1,144 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	gock "github.com/mia-platform/go-crud-service-client/testhelper/gock"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mockListPage(t *testing.T, filter Filter, resources []TestResource) {
+	t.Helper()
+
+	gock.NewGockScope(t, baseURL, http.MethodGet, "").
+		AddMatcher(gock.CrudQueryMatcher(t, gock.Filter(filter))).
+		MatchParam("_l", ".+").
+		Reply(200).
+		JSON(resources)
+}
+
+func TestPager(t *testing.T) {
+	ctx := context.Background()
+	client := getClient(t)
+
+	page1 := []TestResource{{ID: "1"}, {ID: "2"}}
+	page2 := []TestResource{{ID: "3"}, {ID: "4"}}
+	page3 := []TestResource{{ID: "5"}}
+
+	t.Run("iterate all the pages", func(t *testing.T) {
+		sort := "_id"
+		mockListPage(t, Filter{Sort: sort, Limit: 2}, page1)
+		mockListPage(t, Filter{Sort: sort, Limit: 2, Skip: 2}, page2)
+		mockListPage(t, Filter{Sort: sort, Limit: 2, Skip: 4}, page3)
+
+		pager := NewPager[TestResource](client, Options{Filter: Filter{Sort: sort, Limit: 100}}, 2)
+		require.Equal(t, 0, pager.Page())
+
+		ids := []string{}
+		err := pager.ForEach(ctx, func(resource TestResource) error {
+			ids = append(ids, resource.ID)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"1", "2", "3", "4", "5"}, ids)
+		require.Equal(t, 3, pager.Page())
+		require.True(t, pager.Done())
+
+		resources, err := pager.NextPage(ctx)
+		require.NoError(t, err)
+		require.Empty(t, resources)
+	})
+
+	t.Run("start from skip and stop on empty page", func(t *testing.T) {
+		mockListPage(t, Filter{Limit: 2, Skip: 10}, page1)
+		mockListPage(t, Filter{Limit: 2, Skip: 12}, []TestResource{})
+
+		pager := NewPager[TestResource](client, Options{Filter: Filter{Skip: 10}}, 2)
+
+		resources, err := pager.NextPage(ctx)
+		require.NoError(t, err)
+		require.Equal(t, page1, resources)
+		require.Equal(t, 1, pager.Page())
+		require.False(t, pager.Done())
+
+		resources, err = pager.NextPage(ctx)
+		require.NoError(t, err)
+		require.Empty(t, resources)
+		require.Equal(t, 2, pager.Page())
+		require.True(t, pager.Done())
+	})
+
+	t.Run("default page size", func(t *testing.T) {
+		mockListPage(t, Filter{Limit: DefaultPageSize}, page1)
+
+		pager := NewPager[TestResource](client, Options{}, 0)
+		resources, err := pager.NextPage(ctx)
+		require.NoError(t, err)
+		require.Equal(t, page1, resources)
+		require.True(t, pager.Done())
+	})
+
+	t.Run("stop on callback error", func(t *testing.T) {
+		mockListPage(t, Filter{Limit: 2}, page1)
+
+		expectedErr := errors.New("stop")
+		pager := NewPager[TestResource](client, Options{}, 2)
+		count := 0
+		err := pager.ForEach(ctx, func(resource TestResource) error {
+			count++
+			return expectedErr
+		})
+		require.ErrorIs(t, err, expectedErr)
+		require.Equal(t, 1, count)
+	})
+
+	t.Run("stop on client error", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			Reply(500).
+			JSON(CrudErrorResponse{Message: "broken", StatusCode: 500})
+
+		pager := NewPager[TestResource](client, Options{}, 2)
+		err := pager.ForEach(ctx, func(resource TestResource) error { return nil })
+		require.EqualError(t, err, "broken")
+		require.Equal(t, 0, pager.Page())
+	})
+
+	t.Run("stop on context cancellation", func(t *testing.T) {
+		mockListPage(t, Filter{Limit: 2}, page1)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		pager := NewPager[TestResource](client, Options{}, 2)
+		count := 0
+		err := pager.ForEach(ctx, func(resource TestResource) error {
+			count++
+			cancel()
+			return nil
+		})
+		require.ErrorIs(t, err, context.Canceled)
+		require.Equal(t, 1, count)
+
+		_, err = pager.NextPage(ctx)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
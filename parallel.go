@@ -0,0 +1,253 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// DefaultExportPartitions is the number of `_id` ranges exported concurrently by
+// ExportParallel if not specified.
+const DefaultExportPartitions = 4
+
+// ParallelExportOptions configures ExportParallel.
+type ParallelExportOptions struct {
+	// Partitions is the number of `_id` ranges the collection is split into.
+	// Default is DefaultExportPartitions.
+	Partitions int
+	// Workers is the max number of ranges exported at the same time.
+	// Default is the number of partitions.
+	Workers int
+	// Ordered delivers the resources sorted by `_id`. The ranges are still exported
+	// concurrently, but a range is buffered only up to DefaultPageSize resources
+	// while the previous ones are delivered.
+	Ordered bool
+}
+
+type exportRange struct {
+	from json.RawMessage
+	to   json.RawMessage
+}
+
+// ExportParallel exports the resources splitting them in `_id` ranges, which are
+// exported concurrently calling ExportStream. The bounds of the ranges are sampled
+// with Count and List, so the ranges have about the same number of documents.
+//
+// The resources of all the ranges are merged in a single stream: fn is never called
+// concurrently. If fn or the export of a range fails, the other exports are stopped
+// and the first error is returned. Skip and Limit of the filter are not supported.
+func ExportParallel[Resource any](ctx context.Context, client CrudClient[Resource], options Options, parallelOptions ParallelExportOptions, fn func(resource Resource) error) (ExportProgress, error) {
+	if options.Filter.Skip != 0 || options.Filter.Limit != 0 {
+		return ExportProgress{}, fmt.Errorf("%w: skip and limit are not supported by parallel export", ErrInvalidFilter)
+	}
+	partitions := parallelOptions.Partitions
+	if partitions <= 0 {
+		partitions = DefaultExportPartitions
+	}
+	workers := parallelOptions.Workers
+	if workers <= 0 {
+		workers = partitions
+	}
+	if parallelOptions.Ordered {
+		options.Filter.Sort = "_id"
+	}
+
+	ranges, err := exportRanges(ctx, client, options, partitions)
+	if err != nil || len(ranges) == 0 {
+		return ExportProgress{}, err
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		progress ExportProgress
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+		cancel()
+	}
+
+	channels := make([]chan Resource, len(ranges))
+	shared := make(chan Resource, DefaultPageSize)
+	for i := range channels {
+		channels[i] = shared
+		if parallelOptions.Ordered {
+			channels[i] = make(chan Resource, DefaultPageSize)
+		}
+	}
+
+	// the ranges are started in order, so that when ordered the range being
+	// delivered is always running or completed.
+	dispatched := make(chan struct{})
+	go func() {
+		defer close(dispatched)
+
+		var wg sync.WaitGroup
+		defer func() {
+			wg.Wait()
+			if !parallelOptions.Ordered {
+				close(shared)
+			}
+		}()
+
+		semaphore := make(chan struct{}, workers)
+		for i, r := range ranges {
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			wg.Add(1)
+			go func(out chan Resource, r exportRange) {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+				if parallelOptions.Ordered {
+					defer close(out)
+				}
+
+				rangeProgress, err := client.ExportStream(ctx, exportRangeOptions(options, r), func(resource Resource) error {
+					select {
+					case out <- resource:
+						return nil
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				})
+
+				mu.Lock()
+				progress.Bytes += rangeProgress.Bytes
+				mu.Unlock()
+				if err != nil {
+					fail(err)
+				}
+			}(channels[i], r)
+		}
+	}()
+
+	delivered := 0
+	deliver := func(in chan Resource) bool {
+		for {
+			select {
+			case resource, ok := <-in:
+				if !ok {
+					return true
+				}
+				if err := fn(resource); err != nil {
+					fail(err)
+					return false
+				}
+				delivered++
+			case <-ctx.Done():
+				return false
+			}
+		}
+	}
+	if parallelOptions.Ordered {
+		for _, in := range channels {
+			if !deliver(in) {
+				break
+			}
+		}
+	} else {
+		deliver(shared)
+	}
+
+	cancel()
+	<-dispatched
+
+	mu.Lock()
+	defer mu.Unlock()
+	progress.Documents = delivered
+	if firstErr == nil {
+		firstErr = parent.Err()
+	}
+	return progress, firstErr
+}
+
+// exportRanges splits the resources matching the filter in partitions, sampling the
+// `_id`s at the bounds of the partitions. Duplicated bounds are removed, so fewer
+// ranges are returned when there are few resources.
+func exportRanges[Resource any](ctx context.Context, client CrudClient[Resource], options Options, partitions int) ([]exportRange, error) {
+	count, err := client.Count(ctx, options)
+	if err != nil || count == 0 {
+		return nil, err
+	}
+
+	bounds := []json.RawMessage{}
+	for i := 1; i < partitions; i++ {
+		skip := i * count / partitions
+		if skip == 0 {
+			continue
+		}
+
+		sampleOptions := options
+		sampleOptions.Filter.Sort = "_id"
+		sampleOptions.Filter.Skip = skip
+		sampleOptions.Filter.Limit = 1
+		sampleOptions.Filter.Projection = []string{"_id"}
+
+		resources, err := client.List(ctx, sampleOptions)
+		if err != nil {
+			return nil, err
+		}
+		if len(resources) == 0 {
+			break
+		}
+		id, err := resourceID(resources[0])
+		if err != nil {
+			return nil, err
+		}
+		if len(bounds) > 0 && bytes.Equal(bounds[len(bounds)-1], id) {
+			continue
+		}
+		bounds = append(bounds, id)
+	}
+
+	ranges := make([]exportRange, 0, len(bounds)+1)
+	var from json.RawMessage
+	for _, bound := range bounds {
+		ranges = append(ranges, exportRange{from: from, to: bound})
+		from = bound
+	}
+	return append(ranges, exportRange{from: from}), nil
+}
+
+func exportRangeOptions(options Options, r exportRange) Options {
+	condition := map[string]any{}
+	if r.from != nil {
+		condition["$gte"] = r.from
+	}
+	if r.to != nil {
+		condition["$lt"] = r.to
+	}
+	if len(condition) > 0 {
+		options.Filter.MongoQuery = andMongoQuery(options.Filter.MongoQuery, map[string]any{"_id": condition})
+	}
+	return options
+}
@@ -0,0 +1,181 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"sync"
+	"testing"
+
+	gock "github.com/mia-platform/go-crud-service-client/testhelper/gock"
+
+	h2gock "github.com/h2non/gock"
+	"github.com/stretchr/testify/require"
+)
+
+func mockExportRanges(t *testing.T) {
+	t.Helper()
+
+	gock.NewGockScope(t, baseURL, http.MethodGet, "count").
+		Reply(200).
+		JSON("6")
+	gock.NewGockScope(t, baseURL, http.MethodGet, "").
+		MatchParam("_sk", "^2$").
+		MatchParam("_l", "^1$").
+		MatchParam("_s", "^_id$").
+		MatchParam("_p", "^_id$").
+		Reply(200).
+		JSON([]TestResource{{ID: "3"}})
+	gock.NewGockScope(t, baseURL, http.MethodGet, "").
+		MatchParam("_sk", "^4$").
+		Reply(200).
+		JSON([]TestResource{{ID: "5"}})
+}
+
+func mockExportRange(t *testing.T, query string, body string) {
+	t.Helper()
+
+	gock.NewGockScope(t, baseURL, http.MethodGet, "export").
+		MatchParam("_q", "^"+regexp.QuoteMeta(query)+"$").
+		Reply(200).
+		BodyString(body)
+}
+
+func TestExportParallel(t *testing.T) {
+	ctx := context.Background()
+	client := getClient(t)
+
+	mockAllRanges := func(t *testing.T) {
+		mockExportRanges(t)
+		mockExportRange(t, `{"_id":{"$lt":"3"}}`, `{"_id":"1"}`+"\n"+`{"_id":"2"}`)
+		mockExportRange(t, `{"_id":{"$gte":"3","$lt":"5"}}`, `{"_id":"3"}`+"\n"+`{"_id":"4"}`)
+		mockExportRange(t, `{"_id":{"$gte":"5"}}`, `{"_id":"5"}`+"\n"+`{"_id":"6"}`)
+	}
+
+	t.Run("export ranges in order", func(t *testing.T) {
+		mockAllRanges(t)
+
+		ids := []string{}
+		progress, err := ExportParallel[TestResource](ctx, client, Options{}, ParallelExportOptions{
+			Partitions: 3,
+			Workers:    2,
+			Ordered:    true,
+		}, func(resource TestResource) error {
+			ids = append(ids, resource.ID)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"1", "2", "3", "4", "5", "6"}, ids)
+		require.Equal(t, 6, progress.Documents)
+	})
+
+	t.Run("export ranges unordered", func(t *testing.T) {
+		mockAllRanges(t)
+
+		var mu sync.Mutex
+		calls := 0
+		ids := []string{}
+		_, err := ExportParallel[TestResource](ctx, client, Options{}, ParallelExportOptions{Partitions: 3}, func(resource TestResource) error {
+			if !mu.TryLock() {
+				t.Error("fn called concurrently")
+				return nil
+			}
+			defer mu.Unlock()
+			calls++
+			ids = append(ids, resource.ID)
+			return nil
+		})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"1", "2", "3", "4", "5", "6"}, ids)
+		require.Equal(t, 6, calls)
+	})
+
+	t.Run("combine ranges with the mongo query", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "count").
+			Reply(200).
+			JSON("1")
+		mockExportRange(t, `{"field":"v"}`, `{"_id":"1"}`)
+
+		ids := []string{}
+		_, err := ExportParallel[TestResource](ctx, client, Options{
+			Filter: Filter{MongoQuery: map[string]any{"field": "v"}},
+		}, ParallelExportOptions{Partitions: 3}, func(resource TestResource) error {
+			ids = append(ids, resource.ID)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"1"}, ids)
+	})
+
+	t.Run("nothing to export", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "count").
+			Reply(200).
+			JSON("0")
+
+		progress, err := ExportParallel[TestResource](ctx, client, Options{}, ParallelExportOptions{}, func(resource TestResource) error {
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, ExportProgress{}, progress)
+	})
+
+	t.Run("throws when a range fails", func(t *testing.T) {
+		mockExportRanges(t)
+		mockExportRange(t, `{"_id":{"$lt":"3"}}`, `{"_id":"1"}`)
+		mockExportRange(t, `{"_id":{"$gte":"3","$lt":"5"}}`, `{"_id":"3"}`)
+		gock.NewGockScope(t, baseURL, http.MethodGet, "export").
+			MatchParam("_q", "^"+regexp.QuoteMeta(`{"_id":{"$gte":"5"}}`)+"$").
+			Reply(500).
+			AddHeader("Content-Type", "application/json").
+			BodyString(`{"message":"error message"}`)
+
+		_, err := ExportParallel[TestResource](ctx, client, Options{}, ParallelExportOptions{Partitions: 3}, func(resource TestResource) error {
+			return nil
+		})
+		require.ErrorIs(t, err, ErrResponse)
+		require.EqualError(t, err, "error message")
+		// the other ranges may be stopped before their request
+		h2gock.Flush()
+	})
+
+	t.Run("stop when callback fails", func(t *testing.T) {
+		mockAllRanges(t)
+
+		expectedErr := errors.New("stop")
+		progress, err := ExportParallel[TestResource](ctx, client, Options{}, ParallelExportOptions{
+			Partitions: 3,
+			Ordered:    true,
+		}, func(resource TestResource) error {
+			if resource.ID == "2" {
+				return expectedErr
+			}
+			return nil
+		})
+		require.ErrorIs(t, err, expectedErr)
+		require.Equal(t, 1, progress.Documents)
+		h2gock.Flush()
+	})
+
+	t.Run("throws with skip or limit", func(t *testing.T) {
+		_, err := ExportParallel[TestResource](ctx, client, Options{Filter: Filter{Skip: 2}}, ParallelExportOptions{}, func(resource TestResource) error {
+			return nil
+		})
+		require.ErrorIs(t, err, ErrInvalidFilter)
+	})
+}
@@ -0,0 +1,224 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var reservedQueryParams = map[string]bool{
+	"_q":  true,
+	"_p":  true,
+	"_l":  true,
+	"_sk": true,
+	"_s":  true,
+	"_st": true,
+}
+
+var logicalOperators = map[string]bool{
+	"$and": true,
+	"$or":  true,
+	"$nor": true,
+}
+
+// FilterPolicy declares what a query string parsed with ParseFilter is allowed to contain.
+type FilterPolicy struct {
+	// AllowedFields are the fields usable in the query params, `_q`, `_p` and `_s`.
+	// A nested field (e.g. `a.b`) is allowed if it or one of its parents is in the list.
+	AllowedFields []string
+	// AllowedOperators are the MongoDB operators usable in `_q`, e.g. `$gt` or `$in`.
+	// Logical operators like `$or` must be listed too.
+	AllowedOperators []string
+	// MaxLimit, if set, caps the `_l` param. If `_l` is missing, it is used as limit.
+	MaxLimit int
+	// DefaultSort is used if the `_s` param is missing.
+	DefaultSort string
+}
+
+func (p FilterPolicy) isFieldAllowed(field string) bool {
+	for _, allowed := range p.AllowedFields {
+		if field == allowed || strings.HasPrefix(field, allowed+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func (p FilterPolicy) isOperatorAllowed(operator string) bool {
+	for _, allowed := range p.AllowedOperators {
+		if operator == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseFilter converts the query string of an incoming request to a Filter, using the
+// same params of crud-service: `_q`, `_p`, `_l`, `_sk`, `_s`, `_st` and the field names.
+// The query must respect the policy, otherwise an error wrapping ErrInvalidFilter is returned.
+func ParseFilter(values url.Values, policy FilterPolicy) (Filter, error) {
+	filter := Filter{}
+
+	for _, param := range sortedKeys(values) {
+		if reservedQueryParams[param] {
+			continue
+		}
+		if !policy.isFieldAllowed(param) {
+			return Filter{}, fmt.Errorf("%w: field %s not allowed", ErrInvalidFilter, param)
+		}
+		if filter.Fields == nil {
+			filter.Fields = map[string]string{}
+		}
+		filter.Fields[param] = values.Get(param)
+	}
+
+	if rawQuery := values.Get("_q"); rawQuery != "" {
+		if err := json.Unmarshal([]byte(rawQuery), &filter.MongoQuery); err != nil {
+			return Filter{}, fmt.Errorf("%w: _q is not a valid JSON object: %s", ErrInvalidFilter, err)
+		}
+		if err := policy.checkMongoQuery(filter.MongoQuery, ""); err != nil {
+			return Filter{}, err
+		}
+	}
+
+	if rawProjection := values.Get("_p"); rawProjection != "" {
+		for _, field := range strings.Split(rawProjection, ",") {
+			if !policy.isFieldAllowed(field) {
+				return Filter{}, fmt.Errorf("%w: projection of field %s not allowed", ErrInvalidFilter, field)
+			}
+			filter.Projection = append(filter.Projection, field)
+		}
+	}
+
+	limit, err := parseNonNegativeInt(values, "_l")
+	if err != nil {
+		return Filter{}, err
+	}
+	if policy.MaxLimit > 0 && (limit == 0 || limit > policy.MaxLimit) {
+		limit = policy.MaxLimit
+	}
+	filter.Limit = limit
+
+	if filter.Skip, err = parseNonNegativeInt(values, "_sk"); err != nil {
+		return Filter{}, err
+	}
+
+	filter.Sort = policy.DefaultSort
+	if rawSort := values.Get("_s"); rawSort != "" {
+		for _, field := range strings.Split(rawSort, ",") {
+			if !policy.isFieldAllowed(strings.TrimPrefix(field, "-")) {
+				return Filter{}, fmt.Errorf("%w: sort by field %s not allowed", ErrInvalidFilter, field)
+			}
+		}
+		filter.Sort = rawSort
+	}
+
+	if rawState := values.Get("_st"); rawState != "" {
+		for _, state := range strings.Split(rawState, ",") {
+			if !isValidState(State(state)) {
+				return Filter{}, fmt.Errorf("%w: %s", ErrInvalidState, state)
+			}
+			filter.State = append(filter.State, State(state))
+		}
+	}
+
+	return filter, nil
+}
+
+func parseNonNegativeInt(values url.Values, param string) (int, error) {
+	raw := values.Get(param)
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%w: %s must be a non negative integer", ErrInvalidFilter, param)
+	}
+	return n, nil
+}
+
+func (p FilterPolicy) checkMongoQuery(query map[string]any, prefix string) error {
+	for key, value := range query {
+		if strings.HasPrefix(key, "$") {
+			if !p.isOperatorAllowed(key) {
+				return fmt.Errorf("%w: operator %s not allowed", ErrInvalidFilter, key)
+			}
+			if logicalOperators[key] {
+				conditions, ok := value.([]any)
+				if !ok {
+					return fmt.Errorf("%w: operator %s requires an array", ErrInvalidFilter, key)
+				}
+				for _, condition := range conditions {
+					conditionQuery, ok := condition.(map[string]any)
+					if !ok {
+						return fmt.Errorf("%w: operator %s requires an array of objects", ErrInvalidFilter, key)
+					}
+					if err := p.checkMongoQuery(conditionQuery, prefix); err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		}
+
+		field := key
+		if prefix != "" {
+			field = prefix + "." + key
+		}
+		if !p.isFieldAllowed(field) {
+			return fmt.Errorf("%w: field %s not allowed", ErrInvalidFilter, field)
+		}
+		if err := p.checkFieldValue(value, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p FilterPolicy) checkFieldValue(value any, field string) error {
+	operators, ok := value.(map[string]any)
+	if !ok {
+		return nil
+	}
+	for operator, operand := range operators {
+		if !strings.HasPrefix(operator, "$") {
+			// embedded document to compare with equality
+			continue
+		}
+		if !p.isOperatorAllowed(operator) {
+			return fmt.Errorf("%w: operator %s not allowed", ErrInvalidFilter, operator)
+		}
+		switch operator {
+		case "$elemMatch":
+			elemQuery, ok := operand.(map[string]any)
+			if !ok {
+				return fmt.Errorf("%w: operator %s requires an object", ErrInvalidFilter, operator)
+			}
+			if err := p.checkMongoQuery(elemQuery, field); err != nil {
+				return err
+			}
+		case "$not":
+			if err := p.checkFieldValue(operand, field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/mia-platform/go-crud-service-client/internal/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFilter(t *testing.T) {
+	policy := FilterPolicy{
+		AllowedFields:    []string{"name", "age", "nested", "tags"},
+		AllowedOperators: []string{"$gt", "$in", "$or", "$elemMatch"},
+		MaxLimit:         50,
+		DefaultSort:      "name",
+	}
+
+	t.Run("parse all params", func(t *testing.T) {
+		values, err := url.ParseQuery(`name=Alice&_q={"age":{"$gt":18},"$or":[{"nested.field":"a"},{"tags":{"$elemMatch":{"$in":["x"]}}}]}&_p=name,age&_l=10&_sk=20&_s=-age,name&_st=PUBLIC,DRAFT`)
+		require.NoError(t, err)
+
+		filter, err := ParseFilter(values, policy)
+		require.NoError(t, err)
+		require.Equal(t, Filter{
+			Fields: map[string]string{"name": "Alice"},
+			MongoQuery: map[string]any{
+				"age": map[string]any{"$gt": float64(18)},
+				"$or": []any{
+					map[string]any{"nested.field": "a"},
+					map[string]any{"tags": map[string]any{"$elemMatch": map[string]any{"$in": []any{"x"}}}},
+				},
+			},
+			Projection: []string{"name", "age"},
+			Limit:      10,
+			Skip:       20,
+			Sort:       "-age,name",
+			State:      []State{StatePublic, StateDraft},
+		}, filter)
+	})
+
+	t.Run("apply defaults", func(t *testing.T) {
+		filter, err := ParseFilter(url.Values{}, policy)
+		require.NoError(t, err)
+		require.Equal(t, Filter{Limit: 50, Sort: "name"}, filter)
+	})
+
+	t.Run("cap limit", func(t *testing.T) {
+		filter, err := ParseFilter(url.Values{"_l": []string{"1000"}}, policy)
+		require.NoError(t, err)
+		require.Equal(t, 50, filter.Limit)
+	})
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedError string
+	}{
+		{
+			name:          "field not allowed",
+			query:         "password=secret",
+			expectedError: "invalid filter: field password not allowed",
+		},
+		{
+			name:          "field in mongo query not allowed",
+			query:         `_q={"password":"secret"}`,
+			expectedError: "invalid filter: field password not allowed",
+		},
+		{
+			name:          "field in logical operator not allowed",
+			query:         `_q={"$or":[{"name":"a"},{"password":"secret"}]}`,
+			expectedError: "invalid filter: field password not allowed",
+		},
+		{
+			name:          "operator not allowed",
+			query:         `_q={"$where":"sleep(1000)"}`,
+			expectedError: "invalid filter: operator $where not allowed",
+		},
+		{
+			name:          "field operator not allowed",
+			query:         `_q={"name":{"$regex":".*"}}`,
+			expectedError: "invalid filter: operator $regex not allowed",
+		},
+		{
+			name:          "operator in elemMatch not allowed",
+			query:         `_q={"tags":{"$elemMatch":{"label":{"$regex":"a"}}}}`,
+			expectedError: "invalid filter: operator $regex not allowed",
+		},
+		{
+			name:          "invalid mongo query",
+			query:         `_q=[1]`,
+			expectedError: "invalid filter: _q is not a valid JSON object: json: cannot unmarshal array into Go value of type map[string]interface {}",
+		},
+		{
+			name:          "projection not allowed",
+			query:         "_p=name,password",
+			expectedError: "invalid filter: projection of field password not allowed",
+		},
+		{
+			name:          "sort not allowed",
+			query:         "_s=-password",
+			expectedError: "invalid filter: sort by field -password not allowed",
+		},
+		{
+			name:          "invalid limit",
+			query:         "_l=-1",
+			expectedError: "invalid filter: _l must be a non negative integer",
+		},
+		{
+			name:          "invalid skip",
+			query:         "_sk=abc",
+			expectedError: "invalid filter: _sk must be a non negative integer",
+		},
+		{
+			name:          "invalid state",
+			query:         "_st=PUBLIC,ARCHIVED",
+			expectedError: "invalid state: ARCHIVED",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := url.ParseQuery(test.query)
+			require.NoError(t, err)
+
+			filter, err := ParseFilter(values, policy)
+			require.EqualError(t, err, test.expectedError)
+			require.Equal(t, Filter{}, filter)
+		})
+	}
+}
+
+func TestParseFilterRoundTrip(t *testing.T) {
+	filter := Filter{
+		Fields:     map[string]string{"name": "Alice"},
+		MongoQuery: map[string]any{"age": map[string]any{"$gt": float64(18)}},
+		Projection: []string{"name"},
+		Limit:      5,
+		Skip:       10,
+		Sort:       "-age",
+		State:      []State{StateTrash},
+	}
+
+	query := url.Values{}
+	require.NoError(t, convertFilter(query, types.Filter(filter)))
+
+	parsed, err := ParseFilter(query, FilterPolicy{
+		AllowedFields:    []string{"name", "age"},
+		AllowedOperators: []string{"$gt"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, filter, parsed)
+}
@@ -0,0 +1,416 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QuerySyntaxError is returned when a query passed to CompileQuery is not valid.
+type QuerySyntaxError struct {
+	// Position is the 1-based position in the query where the error occurred
+	Position int
+	Message  string
+}
+
+func (e *QuerySyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d: %s", ErrQuerySyntax, e.Position, e.Message)
+}
+
+func (e *QuerySyntaxError) Unwrap() error {
+	return ErrQuerySyntax
+}
+
+var queryComparisonOperators = map[string]string{
+	"==":       "$eq",
+	"!=":       "$ne",
+	"=gt=":     "$gt",
+	">":        "$gt",
+	"=ge=":     "$gte",
+	">=":       "$gte",
+	"=lt=":     "$lt",
+	"<":        "$lt",
+	"=le=":     "$lte",
+	"<=":       "$lte",
+	"=in=":     "$in",
+	"=out=":    "$nin",
+	"=regex=":  "$regex",
+	"=exists=": "$exists",
+}
+
+// CompileQuery compiles a query written with a syntax similar to RSQL/FIQL to a MongoQuery.
+// The query is a list of comparisons `field operator value`, joined by `;` (and) or `,` (or),
+// where `;` has precedence over `,`. Parentheses can be used to group comparisons.
+//
+// Supported operators are `==`, `!=`, `=gt=` (or `>`), `=ge=` (or `>=`), `=lt=` (or `<`),
+// `=le=` (or `<=`), `=in=`, `=out=`, `=regex=` and `=exists=`. `=in=` and `=out=` accept
+// a list of values like `(a,b)`. Values containing reserved characters must be quoted
+// with `"` or `'`.
+//
+// Fields are allowed only if they are in the json representation of Resource, and their
+// values are converted to the type of the Resource field. E.g. with
+//
+//	type Resource struct {
+//		Status string `json:"status"`
+//		Age    int    `json:"age"`
+//		VIP    bool   `json:"vip"`
+//	}
+//
+// the query `status==active;age=gt=18,vip==true` compiles to
+// `{"$or":[{"$and":[{"status":{"$eq":"active"}},{"age":{"$gt":18}}]},{"vip":{"$eq":true}}]}`.
+func CompileQuery[Resource any](query string) (map[string]any, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+
+	p := &queryParser{
+		input:  query,
+		fields: resourceFields(reflect.TypeOf((*Resource)(nil)).Elem()),
+	}
+
+	p.skipSpaces()
+	result, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return nil, p.errorf("unexpected character %q", p.input[p.pos])
+	}
+	return result, nil
+}
+
+type queryParser struct {
+	input  string
+	pos    int
+	fields map[string]reflect.Type
+}
+
+func (p *queryParser) errorAt(pos int, format string, args ...any) error {
+	return &QuerySyntaxError{Position: pos + 1, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *queryParser) errorf(format string, args ...any) error {
+	return p.errorAt(p.pos, format, args...)
+}
+
+func (p *queryParser) skipSpaces() {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *queryParser) consume(token byte) bool {
+	p.skipSpaces()
+	if p.pos < len(p.input) && p.input[p.pos] == token {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) parseOr() (map[string]any, error) {
+	conditions := []any{}
+	for {
+		condition, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+		if !p.consume(',') {
+			break
+		}
+	}
+	if len(conditions) == 1 {
+		return conditions[0].(map[string]any), nil
+	}
+	return map[string]any{"$or": conditions}, nil
+}
+
+func (p *queryParser) parseAnd() (map[string]any, error) {
+	conditions := []map[string]any{}
+	for {
+		condition, err := p.parseConstraint()
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+		if !p.consume(';') {
+			break
+		}
+	}
+	return andMongoQuery(conditions...), nil
+}
+
+func (p *queryParser) parseConstraint() (map[string]any, error) {
+	if p.consume('(') {
+		condition, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(')') {
+			return nil, p.errorf("missing closing parenthesis")
+		}
+		return condition, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *queryParser) parseComparison() (map[string]any, error) {
+	p.skipSpaces()
+	selectorPos := p.pos
+	selector := p.readUnquoted()
+	if selector == "" {
+		return nil, p.errorf("expected field name")
+	}
+
+	fieldType, ok := p.fields[selector]
+	if !ok {
+		return nil, p.errorAt(selectorPos, "unknown field %s", selector)
+	}
+
+	p.skipSpaces()
+	operatorPos := p.pos
+	operator, ok := p.readOperator()
+	if !ok {
+		return nil, p.errorAt(operatorPos, "expected comparison operator")
+	}
+	mongoOperator := queryComparisonOperators[operator]
+
+	p.skipSpaces()
+	if mongoOperator == "$in" || mongoOperator == "$nin" {
+		if !p.consume('(') {
+			return nil, p.errorf("operator %s requires a list of values", operator)
+		}
+		values := []any{}
+		for {
+			value, err := p.parseValue(fieldType)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+			if !p.consume(',') {
+				break
+			}
+		}
+		if !p.consume(')') {
+			return nil, p.errorf("missing closing parenthesis")
+		}
+		return map[string]any{selector: map[string]any{mongoOperator: values}}, nil
+	}
+
+	var valueType reflect.Type
+	switch mongoOperator {
+	case "$regex":
+		valueType = reflect.TypeOf("")
+	case "$exists":
+		valueType = reflect.TypeOf(true)
+	default:
+		valueType = fieldType
+	}
+
+	value, err := p.parseValue(valueType)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{selector: map[string]any{mongoOperator: value}}, nil
+}
+
+func (p *queryParser) readOperator() (string, bool) {
+	rest := p.input[p.pos:]
+	if strings.HasPrefix(rest, "=") {
+		end := strings.IndexByte(rest[1:], '=')
+		if end >= 0 {
+			operator := rest[:end+2]
+			if _, ok := queryComparisonOperators[operator]; ok {
+				p.pos += len(operator)
+				return operator, true
+			}
+		}
+	}
+	for _, operator := range []string{"==", "!=", ">=", "<=", ">", "<"} {
+		if strings.HasPrefix(rest, operator) {
+			p.pos += len(operator)
+			return operator, true
+		}
+	}
+	return "", false
+}
+
+func isQueryReservedChar(c byte) bool {
+	return strings.IndexByte(`"'();,=!<> `, c) >= 0
+}
+
+func (p *queryParser) readUnquoted() string {
+	start := p.pos
+	for p.pos < len(p.input) && !isQueryReservedChar(p.input[p.pos]) {
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+func (p *queryParser) parseValue(valueType reflect.Type) (any, error) {
+	p.skipSpaces()
+	valuePos := p.pos
+
+	var raw string
+	if p.pos < len(p.input) && (p.input[p.pos] == '"' || p.input[p.pos] == '\'') {
+		quote := p.input[p.pos]
+		p.pos++
+		var value strings.Builder
+		for {
+			if p.pos >= len(p.input) {
+				return nil, p.errorAt(valuePos, "unterminated quoted value")
+			}
+			c := p.input[p.pos]
+			if c == '\\' && p.pos+1 < len(p.input) {
+				value.WriteByte(p.input[p.pos+1])
+				p.pos += 2
+				continue
+			}
+			p.pos++
+			if c == quote {
+				break
+			}
+			value.WriteByte(c)
+		}
+		raw = value.String()
+	} else {
+		raw = p.readUnquoted()
+		if raw == "" {
+			return nil, p.errorf("expected value")
+		}
+	}
+
+	value, err := coerceQueryValue(raw, valueType)
+	if err != nil {
+		return nil, p.errorAt(valuePos, "%s", err)
+	}
+	return value, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func coerceQueryValue(raw string, valueType reflect.Type) (any, error) {
+	if valueType == nil {
+		return raw, nil
+	}
+	if valueType == timeType {
+		value, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q", raw)
+		}
+		return value, nil
+	}
+
+	switch valueType.Kind() {
+	case reflect.Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean %q", raw)
+		}
+		return value, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value, err := strconv.ParseInt(raw, 10, valueType.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", raw)
+		}
+		return value, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value, err := strconv.ParseUint(raw, 10, valueType.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("invalid unsigned integer %q", raw)
+		}
+		return value, nil
+	case reflect.Float32, reflect.Float64:
+		value, err := strconv.ParseFloat(raw, valueType.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", raw)
+		}
+		return value, nil
+	default:
+		return raw, nil
+	}
+}
+
+// resourceFields returns the json paths of the fields of the resource type, with the
+// type of the values to use in a query. For slices, the type is the one of the elements.
+func resourceFields(resourceType reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	collectResourceFields(fields, resourceType, "", map[reflect.Type]bool{})
+	return fields
+}
+
+func collectResourceFields(fields map[string]reflect.Type, t reflect.Type, prefix string, visited map[reflect.Type]bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType || visited[t] {
+		return
+	}
+	visited[t] = true
+	defer delete(visited, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, ok := jsonFieldName(field)
+		if !ok {
+			continue
+		}
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		if field.Anonymous && name == "" {
+			collectResourceFields(fields, fieldType, prefix, visited)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		path := prefix + name
+		valueType := fieldType
+		if valueType.Kind() == reflect.Slice || valueType.Kind() == reflect.Array {
+			valueType = valueType.Elem()
+			for valueType.Kind() == reflect.Pointer {
+				valueType = valueType.Elem()
+			}
+		}
+		fields[path] = valueType
+		collectResourceFields(fields, valueType, path+".", visited)
+	}
+}
+
+// jsonFieldName returns the name used in json for the struct field. An empty name with
+// true means that the name is not set by the json tag.
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() && !field.Anonymous {
+		return "", false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	return name, true
+}
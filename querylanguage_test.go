@@ -0,0 +1,201 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type queryResource struct {
+	Metadata
+	Status  string        `json:"status"`
+	Age     int           `json:"age"`
+	VIP     bool          `json:"vip"`
+	Score   float64       `json:"score"`
+	Tags    []string      `json:"tags"`
+	Address *queryAddress `json:"address,omitempty"`
+	Secret  string        `json:"-"`
+	Items   []*queryItem  `json:"items"`
+}
+
+type queryAddress struct {
+	City string `json:"city"`
+}
+
+type queryItem struct {
+	Age int `json:"age"`
+}
+
+func TestCompileQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected map[string]any
+	}{
+		{
+			name:     "empty query",
+			query:    "  ",
+			expected: nil,
+		},
+		{
+			name:  "single comparison",
+			query: "status==active",
+			expected: map[string]any{
+				"status": map[string]any{"$eq": "active"},
+			},
+		},
+		{
+			name:  "and has precedence over or",
+			query: "status==active;age=gt=18,vip==true",
+			expected: map[string]any{
+				"$or": []any{
+					map[string]any{
+						"$and": []any{
+							map[string]any{"status": map[string]any{"$eq": "active"}},
+							map[string]any{"age": map[string]any{"$gt": int64(18)}},
+						},
+					},
+					map[string]any{"vip": map[string]any{"$eq": true}},
+				},
+			},
+		},
+		{
+			name:  "parentheses",
+			query: "status!=banned;(age>=18 , score<2.5)",
+			expected: map[string]any{
+				"$and": []any{
+					map[string]any{"status": map[string]any{"$ne": "banned"}},
+					map[string]any{
+						"$or": []any{
+							map[string]any{"age": map[string]any{"$gte": int64(18)}},
+							map[string]any{"score": map[string]any{"$lt": 2.5}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "lists and quoted values",
+			query: `tags=in=(a,"b,c",'d e');status=out=("x")`,
+			expected: map[string]any{
+				"$and": []any{
+					map[string]any{"tags": map[string]any{"$in": []any{"a", "b,c", "d e"}}},
+					map[string]any{"status": map[string]any{"$nin": []any{"x"}}},
+				},
+			},
+		},
+		{
+			name:  "nested, embedded and date fields",
+			query: `address.city=regex='^Mil';updatedAt=le=2023-01-02T10:00:00Z;__STATE__==DRAFT;items.age=lt=3;address=exists=true`,
+			expected: map[string]any{
+				"$and": []any{
+					map[string]any{"address.city": map[string]any{"$regex": "^Mil"}},
+					map[string]any{"updatedAt": map[string]any{"$lte": time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC)}},
+					map[string]any{"__STATE__": map[string]any{"$eq": "DRAFT"}},
+					map[string]any{"items.age": map[string]any{"$lt": int64(3)}},
+					map[string]any{"address": map[string]any{"$exists": true}},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, err := CompileQuery[queryResource](test.query)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, query)
+		})
+	}
+
+	errorTests := []struct {
+		name          string
+		query         string
+		expectedError string
+	}{
+		{
+			name:          "unknown field",
+			query:         "status==a;password==secret",
+			expectedError: "query syntax error at position 11: unknown field password",
+		},
+		{
+			name:          "field excluded from json",
+			query:         "Secret==a",
+			expectedError: "query syntax error at position 1: unknown field Secret",
+		},
+		{
+			name:          "missing operator",
+			query:         "status",
+			expectedError: "query syntax error at position 7: expected comparison operator",
+		},
+		{
+			name:          "unknown operator",
+			query:         "age=foo=3",
+			expectedError: "query syntax error at position 4: expected comparison operator",
+		},
+		{
+			name:          "invalid integer",
+			query:         "age=gt=old",
+			expectedError: "query syntax error at position 8: invalid integer \"old\"",
+		},
+		{
+			name:          "invalid boolean",
+			query:         "vip==maybe",
+			expectedError: "query syntax error at position 6: invalid boolean \"maybe\"",
+		},
+		{
+			name:          "invalid date",
+			query:         "createdAt=gt=yesterday",
+			expectedError: "query syntax error at position 14: invalid date \"yesterday\"",
+		},
+		{
+			name:          "missing value",
+			query:         "status==",
+			expectedError: "query syntax error at position 9: expected value",
+		},
+		{
+			name:          "unterminated quote",
+			query:         `status=="active`,
+			expectedError: "query syntax error at position 9: unterminated quoted value",
+		},
+		{
+			name:          "missing closing parenthesis",
+			query:         "(status==a,vip==true",
+			expectedError: "query syntax error at position 21: missing closing parenthesis",
+		},
+		{
+			name:          "in without list",
+			query:         "tags=in=a",
+			expectedError: "query syntax error at position 9: operator =in= requires a list of values",
+		},
+		{
+			name:          "trailing characters",
+			query:         "status==a)",
+			expectedError: "query syntax error at position 10: unexpected character ')'",
+		},
+	}
+
+	for _, test := range errorTests {
+		t.Run(test.name, func(t *testing.T) {
+			query, err := CompileQuery[queryResource](test.query)
+			require.ErrorIs(t, err, ErrQuerySyntax)
+			require.EqualError(t, err, test.expectedError)
+			require.Nil(t, query)
+		})
+	}
+}
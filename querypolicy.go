@@ -0,0 +1,198 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"fmt"
+	"reflect"
+	"regexp/syntax"
+	"strings"
+)
+
+// Rules checked by QueryPolicy, reported in QueryPolicyError
+const (
+	RuleMaxDepth          = "max-depth"
+	RuleMaxInSize         = "max-in-size"
+	RuleBannedOperator    = "banned-operator"
+	RuleUnanchoredRegex   = "unanchored-regex"
+	RuleCatastrophicRegex = "catastrophic-regex"
+	RuleRequiredLimit     = "required-limit"
+)
+
+// QueryPolicy defines the limits that the filters must respect to be sent to crud-service.
+// The zero value of each field disables the related check.
+type QueryPolicy struct {
+	// MaxDepth is the max number of nested objects in the MongoQuery
+	MaxDepth int
+	// MaxInSize is the max number of values accepted by `$in` and `$nin`
+	MaxInSize int
+	// BannedOperators are the operators not accepted in the MongoQuery,
+	// e.g. `$where`, `$function` or `$expr`
+	BannedOperators []string
+	// DisallowUnanchoredRegex rejects `$regex` not starting with `^`
+	DisallowUnanchoredRegex bool
+	// DisallowCatastrophicRegex rejects `$regex` with nested quantifiers (e.g. `(a+)+`),
+	// which could cause catastrophic backtracking. Regex that can not be analyzed are rejected too.
+	DisallowCatastrophicRegex bool
+	// RequireListLimit rejects List calls without a limit
+	RequireListLimit bool
+}
+
+// QueryPolicyError is returned when a filter violates the QueryPolicy of the client.
+type QueryPolicyError struct {
+	// Rule is the violated rule, e.g. RuleMaxDepth
+	Rule string
+	// Path is the position in the MongoQuery of the violation
+	Path    string
+	Message string
+}
+
+func (e *QueryPolicyError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%s: %s: %s", ErrQueryPolicy, e.Rule, e.Message)
+	}
+	return fmt.Sprintf("%s: %s at %s: %s", ErrQueryPolicy, e.Rule, e.Path, e.Message)
+}
+
+func (e *QueryPolicyError) Unwrap() error {
+	return ErrQueryPolicy
+}
+
+// Check validates the filter against the policy. If requireLimit is true and
+// RequireListLimit is set, the filter must have a limit.
+func (p QueryPolicy) Check(filter Filter, requireLimit bool) error {
+	if requireLimit && p.RequireListLimit && filter.Limit <= 0 {
+		return &QueryPolicyError{Rule: RuleRequiredLimit, Message: "limit is required"}
+	}
+	return p.CheckMongoQuery(filter.MongoQuery)
+}
+
+// CheckMongoQuery validates the MongoQuery against the policy.
+func (p QueryPolicy) CheckMongoQuery(query map[string]any) error {
+	if query == nil {
+		return nil
+	}
+	return p.checkValue(query, "", 0)
+}
+
+func (p QueryPolicy) checkValue(value any, path string, depth int) error {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Map:
+		depth++
+		if p.MaxDepth > 0 && depth > p.MaxDepth {
+			return &QueryPolicyError{Rule: RuleMaxDepth, Path: path, Message: fmt.Sprintf("max depth is %d", p.MaxDepth)}
+		}
+		iter := rv.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			item := iter.Value().Interface()
+			itemPath := joinQueryPath(path, key)
+
+			if err := p.checkOperator(key, item, itemPath); err != nil {
+				return err
+			}
+			if err := p.checkValue(item, itemPath, depth); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			return nil
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if err := p.checkValue(rv.Index(i).Interface(), fmt.Sprintf("%s[%d]", path, i), depth); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p QueryPolicy) checkOperator(operator string, operand any, path string) error {
+	if !strings.HasPrefix(operator, "$") {
+		return nil
+	}
+
+	for _, banned := range p.BannedOperators {
+		if operator == banned {
+			return &QueryPolicyError{Rule: RuleBannedOperator, Path: path, Message: fmt.Sprintf("operator %s is not allowed", operator)}
+		}
+	}
+
+	switch operator {
+	case "$in", "$nin":
+		rv := reflect.ValueOf(operand)
+		if p.MaxInSize > 0 && (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Len() > p.MaxInSize {
+			return &QueryPolicyError{Rule: RuleMaxInSize, Path: path, Message: fmt.Sprintf("%d values exceed the max of %d", rv.Len(), p.MaxInSize)}
+		}
+	case "$regex":
+		pattern, ok := operand.(string)
+		if !ok {
+			return nil
+		}
+		if p.DisallowUnanchoredRegex && !strings.HasPrefix(pattern, "^") {
+			return &QueryPolicyError{Rule: RuleUnanchoredRegex, Path: path, Message: fmt.Sprintf("regex %q must start with ^", pattern)}
+		}
+		if p.DisallowCatastrophicRegex {
+			if err := checkCatastrophicRegex(pattern); err != nil {
+				return &QueryPolicyError{Rule: RuleCatastrophicRegex, Path: path, Message: err.Error()}
+			}
+		}
+	}
+	return nil
+}
+
+func checkCatastrophicRegex(pattern string) error {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return fmt.Errorf("regex %q can not be analyzed: %s", pattern, err)
+	}
+	if hasNestedQuantifier(re, false) {
+		return fmt.Errorf("regex %q has nested quantifiers", pattern)
+	}
+	return nil
+}
+
+func hasNestedQuantifier(re *syntax.Regexp, insideQuantifier bool) bool {
+	quantifier := isUnboundedRepeat(re)
+	if quantifier && insideQuantifier {
+		return true
+	}
+	for _, sub := range re.Sub {
+		if hasNestedQuantifier(sub, insideQuantifier || quantifier) {
+			return true
+		}
+	}
+	return false
+}
+
+func isUnboundedRepeat(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpStar, syntax.OpPlus:
+		return true
+	case syntax.OpRepeat:
+		return re.Max == -1 || re.Max > 1
+	}
+	return false
+}
+
+func joinQueryPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,197 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	gock "github.com/mia-platform/go-crud-service-client/testhelper/gock"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryPolicyCheck(t *testing.T) {
+	policy := QueryPolicy{
+		MaxDepth:                  3,
+		MaxInSize:                 2,
+		BannedOperators:           []string{"$where", "$function", "$expr"},
+		DisallowUnanchoredRegex:   true,
+		DisallowCatastrophicRegex: true,
+		RequireListLimit:          true,
+	}
+
+	t.Run("valid filter", func(t *testing.T) {
+		err := policy.Check(Filter{
+			MongoQuery: map[string]any{
+				"$or": []any{
+					map[string]any{"name": map[string]any{"$regex": "^abc.*"}},
+					map[string]any{"tags": map[string]any{"$in": []string{"a", "b"}}},
+				},
+			},
+			Limit: 10,
+		}, true)
+		require.NoError(t, err)
+	})
+
+	t.Run("empty filter without required limit", func(t *testing.T) {
+		require.NoError(t, policy.Check(Filter{}, false))
+	})
+
+	t.Run("zero policy accepts everything", func(t *testing.T) {
+		err := QueryPolicy{}.Check(Filter{
+			MongoQuery: map[string]any{"$where": "sleep(100)", "a": map[string]any{"$regex": "(a+)+"}},
+		}, true)
+		require.NoError(t, err)
+	})
+
+	tests := []struct {
+		name          string
+		filter        Filter
+		requireLimit  bool
+		expectedRule  string
+		expectedError string
+	}{
+		{
+			name:          "missing limit",
+			filter:        Filter{},
+			requireLimit:  true,
+			expectedRule:  RuleRequiredLimit,
+			expectedError: "query policy violation: required-limit: limit is required",
+		},
+		{
+			name: "too deep",
+			filter: Filter{MongoQuery: map[string]any{
+				"$and": []any{map[string]any{"a": map[string]any{"$elemMatch": map[string]any{"b": 1}}}},
+			}},
+			expectedRule:  RuleMaxDepth,
+			expectedError: "query policy violation: max-depth at $and[0].a.$elemMatch: max depth is 3",
+		},
+		{
+			name:          "too many values in $in",
+			filter:        Filter{MongoQuery: map[string]any{"a": map[string]any{"$in": []any{1, 2, 3}}}},
+			expectedRule:  RuleMaxInSize,
+			expectedError: "query policy violation: max-in-size at a.$in: 3 values exceed the max of 2",
+		},
+		{
+			name:          "too many values in $nin",
+			filter:        Filter{MongoQuery: map[string]any{"a": map[string]any{"$nin": []string{"1", "2", "3"}}}},
+			expectedRule:  RuleMaxInSize,
+			expectedError: "query policy violation: max-in-size at a.$nin: 3 values exceed the max of 2",
+		},
+		{
+			name:          "banned operator",
+			filter:        Filter{MongoQuery: map[string]any{"$where": "sleep(1000)"}},
+			expectedRule:  RuleBannedOperator,
+			expectedError: "query policy violation: banned-operator at $where: operator $where is not allowed",
+		},
+		{
+			name:          "banned operator nested",
+			filter:        Filter{MongoQuery: map[string]any{"$or": []any{map[string]any{"$expr": map[string]any{}}}}},
+			expectedRule:  RuleBannedOperator,
+			expectedError: "query policy violation: banned-operator at $or[0].$expr: operator $expr is not allowed",
+		},
+		{
+			name:          "unanchored regex",
+			filter:        Filter{MongoQuery: map[string]any{"name": map[string]any{"$regex": "abc"}}},
+			expectedRule:  RuleUnanchoredRegex,
+			expectedError: `query policy violation: unanchored-regex at name.$regex: regex "abc" must start with ^`,
+		},
+		{
+			name:          "catastrophic regex",
+			filter:        Filter{MongoQuery: map[string]any{"name": map[string]any{"$regex": "^(a+)+$"}}},
+			expectedRule:  RuleCatastrophicRegex,
+			expectedError: `query policy violation: catastrophic-regex at name.$regex: regex "^(a+)+$" has nested quantifiers`,
+		},
+		{
+			name:          "regex that can not be analyzed",
+			filter:        Filter{MongoQuery: map[string]any{"name": map[string]any{"$regex": "^a(?=b)"}}},
+			expectedRule:  RuleCatastrophicRegex,
+			expectedError: "query policy violation: catastrophic-regex at name.$regex: regex \"^a(?=b)\" can not be analyzed: error parsing regexp: invalid or unsupported Perl syntax: `(?=`",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := policy.Check(test.filter, test.requireLimit)
+			require.ErrorIs(t, err, ErrQueryPolicy)
+			require.EqualError(t, err, test.expectedError)
+
+			policyErr := &QueryPolicyError{}
+			require.ErrorAs(t, err, &policyErr)
+			require.Equal(t, test.expectedRule, policyErr.Rule)
+		})
+	}
+}
+
+func TestClientWithQueryPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := NewClient[TestResource](ClientOptions{
+		BaseURL: baseURL,
+		QueryPolicy: &QueryPolicy{
+			BannedOperators:  []string{"$where"},
+			RequireListLimit: true,
+		},
+	})
+	require.NoError(t, err)
+	client := c.(Client[TestResource])
+
+	t.Run("list without limit is rejected", func(t *testing.T) {
+		resources, err := client.List(ctx, Options{})
+		require.ErrorIs(t, err, ErrQueryPolicy)
+		require.Nil(t, resources)
+	})
+
+	t.Run("list with limit is sent", func(t *testing.T) {
+		filter := Filter{Limit: 5}
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			AddMatcher(gock.CrudQueryMatcher(t, gock.Filter(filter))).
+			Reply(200).
+			JSON([]TestResource{})
+
+		resources, err := client.List(ctx, Options{Filter: filter})
+		require.NoError(t, err)
+		require.Equal(t, []TestResource{}, resources)
+	})
+
+	t.Run("count without limit is sent", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "count").
+			Reply(200).
+			JSON(3)
+
+		count, err := client.Count(ctx, Options{})
+		require.NoError(t, err)
+		require.Equal(t, 3, count)
+	})
+
+	t.Run("banned operator is rejected", func(t *testing.T) {
+		count, err := client.DeleteMany(ctx, Options{
+			Filter: Filter{MongoQuery: map[string]any{"$where": "true"}},
+		})
+		require.ErrorIs(t, err, ErrQueryPolicy)
+		require.Equal(t, 0, count)
+	})
+
+	t.Run("banned operator in bulk filters is rejected", func(t *testing.T) {
+		count, err := client.PatchBulk(ctx, PatchBulkBody{
+			{Filter: PatchBulkFilter{MongoQuery: map[string]any{"$where": "true"}}},
+		}, Options{})
+		require.ErrorIs(t, err, ErrQueryPolicy)
+		require.Equal(t, 0, count)
+	})
+}
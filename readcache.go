@@ -0,0 +1,242 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// CacheOptions configures the read-through cache of a client. The results of GetByID,
+// List and Count are cached for the TTL of the operation: a zero TTL disables
+// the cache of the operation.
+//
+// The writes made through the same client invalidate the affected entries: the writes
+// of a single resource invalidate the resource and all the lists and counts, while
+// the other writes invalidate everything. The entries are invalidated changing the
+// generation in their key, so the stale entries are not deleted but expire.
+type CacheOptions struct {
+	// Cache stores the entries. It can be shared by many clients.
+	Cache Cache
+	// GetByIDTTL is the TTL of the resources got by id
+	GetByIDTTL time.Duration
+	// ListTTL is the TTL of the lists
+	ListTTL time.Duration
+	// CountTTL is the TTL of the counts
+	CountTTL time.Duration
+	// NotFoundTTL is the TTL of the not found errors of GetByID. Zero disables
+	// the negative caching.
+	NotFoundTTL time.Duration
+}
+
+const (
+	cacheOperationGetByID = "getById"
+	cacheOperationList    = "list"
+	cacheOperationCount   = "count"
+
+	cacheGenerationQueries   = "queries"
+	cacheGenerationDocuments = "documents"
+)
+
+type clientCache struct {
+	options CacheOptions
+	prefix  string
+}
+
+type cachedNotFound struct {
+	Header       http.Header       `json:"header,omitempty"`
+	ResponseBody CrudErrorResponse `json:"responseBody"`
+	Raw          []byte            `json:"raw"`
+}
+
+func newClientCache(options *CacheOptions, baseURL string) *clientCache {
+	if options == nil || options.Cache == nil {
+		return nil
+	}
+	return &clientCache{options: *options, prefix: baseURL + "|"}
+}
+
+// cachedRead returns the cached result of the operation, or calls fn and caches its result.
+// With Options.SkipCache, fn is always called but its result is still cached.
+func cachedRead[T any](ctx context.Context, cache *clientCache, operation, id string, options Options, fn func() (T, error)) (T, error) {
+	if cache == nil || cache.ttl(operation) <= 0 {
+		return fn()
+	}
+	key, err := cache.key(ctx, operation, id, reflect.TypeOf((*T)(nil)).Elem().String(), options)
+	if err != nil {
+		return fn()
+	}
+
+	if !options.SkipCache {
+		if data, ok, err := cache.options.Cache.Get(ctx, key); err == nil && ok {
+			if value, err, ok := decodeCacheEntry[T](data); ok {
+				return value, err
+			}
+		}
+	}
+
+	value, err := fn()
+	if err != nil {
+		if operation == cacheOperationGetByID && cache.options.NotFoundTTL > 0 {
+			var httpErr *HTTPError
+			if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+				notFound := cachedNotFound{ResponseBody: httpErr.ResponseBody, Raw: httpErr.Raw}
+				if httpErr.Response != nil {
+					notFound.Header = httpErr.Response.Header
+				}
+				if data, marshalErr := json.Marshal(notFound); marshalErr == nil {
+					_ = cache.options.Cache.Set(ctx, key, append([]byte{'n'}, data...), cache.options.NotFoundTTL)
+				}
+			}
+		}
+		return value, err
+	}
+
+	if data, err := json.Marshal(value); err == nil {
+		_ = cache.options.Cache.Set(ctx, key, append([]byte{'v'}, data...), cache.ttl(operation))
+	}
+	return value, nil
+}
+
+// decodeCacheEntry decodes a cached value or not found error, reporting whether the
+// entry is valid. The Response of the not found error is rebuilt with the status and
+// the headers of the original response, and an empty body since it is already read.
+func decodeCacheEntry[T any](data []byte) (T, error, bool) {
+	var value T
+	if len(data) == 0 {
+		return value, nil, false
+	}
+
+	switch data[0] {
+	case 'v':
+		if err := json.Unmarshal(data[1:], &value); err != nil {
+			return value, nil, false
+		}
+		return value, nil, true
+	case 'n':
+		notFound := cachedNotFound{}
+		if err := json.Unmarshal(data[1:], &notFound); err != nil {
+			return value, nil, false
+		}
+		if notFound.Header == nil {
+			notFound.Header = http.Header{}
+		}
+		return value, &HTTPError{
+			Response: &http.Response{
+				Status:     fmt.Sprintf("%d %s", http.StatusNotFound, http.StatusText(http.StatusNotFound)),
+				StatusCode: http.StatusNotFound,
+				Proto:      "HTTP/1.1",
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+				Header:     notFound.Header,
+				Body:       http.NoBody,
+			},
+			StatusCode:   http.StatusNotFound,
+			Err:          ErrResponse,
+			ResponseBody: notFound.ResponseBody,
+			Raw:          notFound.Raw,
+		}, true
+	}
+	return value, nil, false
+}
+
+func (c *clientCache) ttl(operation string) time.Duration {
+	switch operation {
+	case cacheOperationGetByID:
+		return c.options.GetByIDTTL
+	case cacheOperationList:
+		return c.options.ListTTL
+	case cacheOperationCount:
+		return c.options.CountTTL
+	}
+	return 0
+}
+
+// key returns the key of the entry, which contains the generations that are
+// changed by the writes affecting the entry. The result type is part of the key,
+// since the clients of ListAs and GetByIDAs decode the same request into other types.
+func (c *clientCache) key(ctx context.Context, operation, id, resultType string, options Options) (string, error) {
+	request, err := flightKey(operation, id, options)
+	if err != nil {
+		return "", err
+	}
+
+	generations := []string{c.generation(ctx, cacheGenerationQueries)}
+	if operation == cacheOperationGetByID {
+		generations = []string{
+			c.generation(ctx, cacheGenerationDocuments),
+			c.generation(ctx, idGeneration(id)),
+		}
+	}
+
+	hash := sha256.New()
+	for _, generation := range generations {
+		hash.Write([]byte(generation))
+		hash.Write([]byte{0})
+	}
+	hash.Write([]byte(resultType))
+	hash.Write([]byte{0})
+	hash.Write([]byte(request))
+	return c.prefix + operation + "|" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// generation returns the current generation with the given name. A missing generation,
+// also if evicted, is replaced with a new one, so that stale entries are never used.
+func (c *clientCache) generation(ctx context.Context, name string) string {
+	key := c.prefix + "gen|" + name
+	if generation, ok, err := c.options.Cache.Get(ctx, key); err == nil && ok {
+		return string(generation)
+	}
+	return c.newGeneration(ctx, name)
+}
+
+func (c *clientCache) newGeneration(ctx context.Context, name string) string {
+	token := make([]byte, 16)
+	_, _ = rand.Read(token)
+	generation := hex.EncodeToString(token)
+	_ = c.options.Cache.Set(ctx, c.prefix+"gen|"+name, []byte(generation), 0)
+	return generation
+}
+
+// invalidateID invalidates the resource with the given id, and all the lists and counts.
+func (c *clientCache) invalidateID(ctx context.Context, id string) {
+	if c == nil {
+		return
+	}
+	c.newGeneration(ctx, idGeneration(id))
+	c.newGeneration(ctx, cacheGenerationQueries)
+}
+
+// invalidateAll invalidates all the entries.
+func (c *clientCache) invalidateAll(ctx context.Context) {
+	if c == nil {
+		return
+	}
+	c.newGeneration(ctx, cacheGenerationDocuments)
+	c.newGeneration(ctx, cacheGenerationQueries)
+}
+
+func idGeneration(id string) string {
+	return "id|" + normalizeID(id)
+}
@@ -0,0 +1,239 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	gock "github.com/mia-platform/go-crud-service-client/testhelper/gock"
+
+	"github.com/stretchr/testify/require"
+)
+
+func getCachedClient(t *testing.T, options CacheOptions) Client[TestResource] {
+	t.Helper()
+
+	if options.Cache == nil {
+		options.Cache = NewLRUCache(100)
+	}
+	client, err := NewClient[TestResource](ClientOptions{
+		BaseURL: baseURL,
+		Cache:   &options,
+	})
+	require.NoError(t, err)
+
+	return client.(Client[TestResource])
+}
+
+func TestCachedReads(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("cache get by id, list and count", func(t *testing.T) {
+		client := getCachedClient(t, CacheOptions{GetByIDTTL: time.Minute, ListTTL: time.Minute, CountTTL: time.Minute})
+
+		gock.NewGockScope(t, baseURL, http.MethodGet, "my-id").
+			Reply(200).
+			JSON(TestResource{ID: "my-id", Field: "v"})
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			Reply(200).
+			JSON([]TestResource{{ID: "my-id", Field: "v"}})
+		gock.NewGockScope(t, baseURL, http.MethodGet, "count").
+			Reply(200).
+			JSON("1")
+
+		for i := 0; i < 2; i++ {
+			resource, err := client.GetByID(ctx, "my-id", Options{})
+			require.NoError(t, err)
+			require.Equal(t, &TestResource{ID: "my-id", Field: "v"}, resource)
+
+			resources, err := client.List(ctx, Options{})
+			require.NoError(t, err)
+			require.Equal(t, []TestResource{{ID: "my-id", Field: "v"}}, resources)
+
+			count, err := client.Count(ctx, Options{})
+			require.NoError(t, err)
+			require.Equal(t, 1, count)
+		}
+	})
+
+	t.Run("cache by filter and headers", func(t *testing.T) {
+		client := getCachedClient(t, CacheOptions{CountTTL: time.Minute})
+
+		gock.NewGockScope(t, baseURL, http.MethodGet, "count").
+			Times(3).
+			Reply(200).
+			JSON("1")
+
+		_, err := client.Count(ctx, Options{})
+		require.NoError(t, err)
+		_, err = client.Count(ctx, Options{Filter: Filter{Fields: map[string]string{"field": "v"}}})
+		require.NoError(t, err)
+		_, err = client.Count(ctx, Options{Headers: http.Header{"Authorization": []string{"token"}}})
+		require.NoError(t, err)
+	})
+
+	t.Run("do not cache operations without ttl", func(t *testing.T) {
+		client := getCachedClient(t, CacheOptions{GetByIDTTL: time.Minute})
+
+		gock.NewGockScope(t, baseURL, http.MethodGet, "count").
+			Times(2).
+			Reply(200).
+			JSON("1")
+
+		for i := 0; i < 2; i++ {
+			_, err := client.Count(ctx, Options{})
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("cache not found", func(t *testing.T) {
+		client := getCachedClient(t, CacheOptions{GetByIDTTL: time.Minute, NotFoundTTL: time.Minute})
+
+		gock.NewGockScope(t, baseURL, http.MethodGet, "my-id").
+			Reply(404).
+			AddHeader("Content-Type", "application/json").
+			BodyString(`{"message":"not found","statusCode":404}`)
+
+		for i := 0; i < 2; i++ {
+			_, err := client.GetByID(ctx, "my-id", Options{})
+			require.ErrorIs(t, err, ErrResponse)
+			require.EqualError(t, err, "not found")
+
+			httpErr := &HTTPError{}
+			require.ErrorAs(t, err, &httpErr)
+			require.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+			require.NotNil(t, httpErr.Response)
+			require.Equal(t, http.StatusNotFound, httpErr.Response.StatusCode)
+			require.Equal(t, "application/json", httpErr.Response.Header.Get("Content-Type"))
+		}
+	})
+
+	t.Run("skip cache per call and cache the fresh result", func(t *testing.T) {
+		client := getCachedClient(t, CacheOptions{GetByIDTTL: time.Minute, NotFoundTTL: time.Minute})
+
+		gock.NewGockScope(t, baseURL, http.MethodGet, "my-id").
+			Reply(404).
+			AddHeader("Content-Type", "application/json").
+			BodyString(`{"message":"not found","statusCode":404}`)
+		_, err := client.GetByID(ctx, "my-id", Options{})
+		require.ErrorIs(t, err, ErrNotFound)
+
+		gock.NewGockScope(t, baseURL, http.MethodGet, "my-id").
+			Reply(200).
+			JSON(TestResource{ID: "my-id", Field: "v"})
+		resource, err := client.GetByID(ctx, "my-id", Options{SkipCache: true})
+		require.NoError(t, err)
+		require.Equal(t, &TestResource{ID: "my-id", Field: "v"}, resource)
+
+		resource, err = client.GetByID(ctx, "my-id", Options{})
+		require.NoError(t, err)
+		require.Equal(t, &TestResource{ID: "my-id", Field: "v"}, resource)
+	})
+
+	t.Run("do not cache other errors", func(t *testing.T) {
+		client := getCachedClient(t, CacheOptions{GetByIDTTL: time.Minute, NotFoundTTL: time.Minute})
+
+		gock.NewGockScope(t, baseURL, http.MethodGet, "my-id").
+			Times(2).
+			Reply(500).
+			AddHeader("Content-Type", "application/json").
+			BodyString(`{"message":"error"}`)
+
+		for i := 0; i < 2; i++ {
+			_, err := client.GetByID(ctx, "my-id", Options{})
+			require.ErrorIs(t, err, ErrResponse)
+		}
+	})
+
+	t.Run("writes by id invalidate the resource and the queries", func(t *testing.T) {
+		client := getCachedClient(t, CacheOptions{GetByIDTTL: time.Minute, CountTTL: time.Minute})
+
+		gock.NewGockScope(t, baseURL, http.MethodGet, "my-id").
+			Times(2).
+			Reply(200).
+			JSON(TestResource{ID: "my-id"})
+		gock.NewGockScope(t, baseURL, http.MethodGet, "other-id").
+			Reply(200).
+			JSON(TestResource{ID: "other-id"})
+		gock.NewGockScope(t, baseURL, http.MethodGet, "count").
+			Times(2).
+			Reply(200).
+			JSON("2")
+		gock.NewGockScope(t, baseURL, http.MethodDelete, "my-id").
+			Reply(204)
+
+		read := func() {
+			_, err := client.GetByID(ctx, "my-id", Options{})
+			require.NoError(t, err)
+			_, err = client.GetByID(ctx, "other-id", Options{})
+			require.NoError(t, err)
+			_, err = client.Count(ctx, Options{})
+			require.NoError(t, err)
+		}
+
+		read()
+		require.NoError(t, client.DeleteById(ctx, "my-id", Options{}))
+		read()
+	})
+
+	t.Run("other writes invalidate everything", func(t *testing.T) {
+		client := getCachedClient(t, CacheOptions{GetByIDTTL: time.Minute, ListTTL: time.Minute})
+
+		gock.NewGockScope(t, baseURL, http.MethodGet, "my-id").
+			Times(2).
+			Reply(200).
+			JSON(TestResource{ID: "my-id"})
+		gock.NewGockScope(t, baseURL, http.MethodGet, "").
+			Times(2).
+			Reply(200).
+			JSON([]TestResource{{ID: "my-id"}})
+		gock.NewGockScope(t, baseURL, http.MethodPatch, "").
+			Reply(200).
+			JSON("1")
+
+		read := func() {
+			_, err := client.GetByID(ctx, "my-id", Options{})
+			require.NoError(t, err)
+			_, err = client.List(ctx, Options{})
+			require.NoError(t, err)
+		}
+
+		read()
+		_, err := client.PatchMany(ctx, PatchBody{Set: map[string]any{"field": "v"}}, Options{})
+		require.NoError(t, err)
+		read()
+	})
+
+	t.Run("evicted generations do not resurrect stale entries", func(t *testing.T) {
+		cache := NewLRUCache(100)
+		client := getCachedClient(t, CacheOptions{Cache: cache, GetByIDTTL: time.Minute})
+
+		gock.NewGockScope(t, baseURL, http.MethodGet, "my-id").
+			Times(2).
+			Reply(200).
+			JSON(TestResource{ID: "my-id"})
+
+		_, err := client.GetByID(ctx, "my-id", Options{})
+		require.NoError(t, err)
+
+		require.NoError(t, cache.Delete(ctx, baseURL+"|gen|"+idGeneration("my-id")))
+		_, err = client.GetByID(ctx, "my-id", Options{})
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,197 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultCheckpointInterval is the number of documents exported between two
+// checkpoints if not specified.
+const DefaultCheckpointInterval = 1000
+
+// ResumableExportOptions configures ExportResumable.
+type ResumableExportOptions struct {
+	// Store saves the `_id` of the last delivered document. It is required.
+	Store CheckpointStore
+	// CheckpointInterval is the number of documents delivered between two checkpoints.
+	// Default is DefaultCheckpointInterval.
+	CheckpointInterval int
+	// MaxRetries is the number of times the export is resumed after a failure
+	// before returning the error.
+	MaxRetries int
+	// RetryDelay is the time to wait before resuming the export.
+	RetryDelay time.Duration
+}
+
+type exportCheckpoint struct {
+	LastID json.RawMessage `json:"lastId"`
+}
+
+// ExportResumable exports the resources sorted by `_id`, calling fn for each of them
+// like ExportStream, and periodically saves the `_id` of the last delivered document
+// in the checkpoint store. If the store has a checkpoint, the export continues with
+// the documents with `_id` greater than the checkpoint instead of starting over.
+//
+// When the export fails, the checkpoint is saved and the export is resumed up to
+// MaxRetries times; errors returned by fn, client errors of crud-service and
+// cancellations are not retried. When the export completes, the checkpoint is cleared.
+//
+// The Sort of the filter is replaced by `_id`, while Skip and Limit are not supported.
+// The returned progress counts the documents delivered by this call.
+func ExportResumable[Resource any](ctx context.Context, client CrudClient[Resource], options Options, resumeOptions ResumableExportOptions, fn func(resource Resource) error) (ExportProgress, error) {
+	if resumeOptions.Store == nil {
+		return ExportProgress{}, fmt.Errorf("%w: checkpoint store is required", ErrCheckpoint)
+	}
+	if options.Filter.Skip != 0 || options.Filter.Limit != 0 {
+		return ExportProgress{}, fmt.Errorf("%w: skip and limit are not supported by resumable export", ErrInvalidFilter)
+	}
+	interval := resumeOptions.CheckpointInterval
+	if interval <= 0 {
+		interval = DefaultCheckpointInterval
+	}
+
+	lastID, err := loadExportCheckpoint(ctx, resumeOptions.Store)
+	if err != nil {
+		return ExportProgress{}, err
+	}
+
+	progress := ExportProgress{}
+	retries := 0
+	for {
+		var callbackErr error
+		sinceCheckpoint := 0
+		attemptProgress, err := client.ExportStream(ctx, resumeExportOptions(options, lastID), func(resource Resource) error {
+			id, err := resourceID(resource)
+			if err != nil {
+				callbackErr = err
+				return err
+			}
+			if err := fn(resource); err != nil {
+				callbackErr = err
+				return err
+			}
+			lastID = id
+			sinceCheckpoint++
+			if sinceCheckpoint >= interval {
+				sinceCheckpoint = 0
+				if err := saveExportCheckpoint(ctx, resumeOptions.Store, lastID); err != nil {
+					callbackErr = err
+					return err
+				}
+			}
+			return nil
+		})
+		progress.Documents += attemptProgress.Documents
+		progress.Bytes += attemptProgress.Bytes
+
+		if err == nil {
+			return progress, resumeOptions.Store.Clear(ctx)
+		}
+		if lastID != nil {
+			if saveErr := saveExportCheckpoint(ctx, resumeOptions.Store, lastID); saveErr != nil {
+				return progress, errors.Join(err, saveErr)
+			}
+		}
+		if callbackErr != nil || ctx.Err() != nil || retries >= resumeOptions.MaxRetries || !isRetryableExportError(err) {
+			return progress, err
+		}
+		retries++
+
+		timer := time.NewTimer(resumeOptions.RetryDelay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return progress, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func resumeExportOptions(options Options, lastID json.RawMessage) Options {
+	options.Filter.Sort = "_id"
+	options.Filter.Projection = projectionWithID(options.Filter.Projection)
+	if lastID != nil {
+		options.Filter.MongoQuery = andMongoQuery(
+			options.Filter.MongoQuery,
+			map[string]any{"_id": map[string]any{"$gt": lastID}},
+		)
+	}
+	return options
+}
+
+// projectionWithID adds `_id` to a non empty projection, without modifying it.
+func projectionWithID(projection []string) []string {
+	if len(projection) == 0 || contains(projection, "_id") {
+		return projection
+	}
+	return append(append([]string{}, projection...), "_id")
+}
+
+func resourceID(resource any) (json.RawMessage, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	document := struct {
+		ID json.RawMessage `json:"_id"`
+	}{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	if len(document.ID) == 0 || string(document.ID) == "null" {
+		return nil, fmt.Errorf("%w: resource without _id", ErrCheckpoint)
+	}
+	return document.ID, nil
+}
+
+func loadExportCheckpoint(ctx context.Context, store CheckpointStore) (json.RawMessage, error) {
+	data, err := store.Load(ctx)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	checkpoint := exportCheckpoint{}
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCheckpoint, err)
+	}
+	if len(checkpoint.LastID) == 0 || string(checkpoint.LastID) == "null" {
+		return nil, fmt.Errorf("%w: missing lastId", ErrCheckpoint)
+	}
+	return checkpoint.LastID, nil
+}
+
+func saveExportCheckpoint(ctx context.Context, store CheckpointStore, lastID json.RawMessage) error {
+	data, err := json.Marshal(exportCheckpoint{LastID: lastID})
+	if err != nil {
+		return err
+	}
+	return store.Save(ctx, data)
+}
+
+func isRetryableExportError(err error) bool {
+	if errors.Is(err, ErrQueryPolicy) || errors.Is(err, ErrCreateRequest) {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500
+	}
+	return true
+}
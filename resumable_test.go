@@ -0,0 +1,191 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	gock "github.com/mia-platform/go-crud-service-client/testhelper/gock"
+
+	"github.com/stretchr/testify/require"
+)
+
+type memoryCheckpointStore struct {
+	checkpoint []byte
+	saves      []string
+}
+
+func (s *memoryCheckpointStore) Load(_ context.Context) ([]byte, error) {
+	return s.checkpoint, nil
+}
+
+func (s *memoryCheckpointStore) Save(_ context.Context, checkpoint []byte) error {
+	s.checkpoint = checkpoint
+	s.saves = append(s.saves, string(checkpoint))
+	return nil
+}
+
+func (s *memoryCheckpointStore) Clear(_ context.Context) error {
+	s.checkpoint = nil
+	return nil
+}
+
+func TestExportResumable(t *testing.T) {
+	ctx := context.Background()
+	client := getClient(t)
+
+	t.Run("export from the start saving checkpoints", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "export").
+			AddMatcher(gock.CrudQueryMatcher(t, gock.Filter{
+				Sort:       "_id",
+				Projection: []string{"field", "_id"},
+			})).
+			Reply(200).
+			BodyString(`{"_id":"1"}` + "\n" + `{"_id":"2"}` + "\n" + `{"_id":"3"}`)
+
+		store := &memoryCheckpointStore{}
+		ids := []string{}
+		progress, err := ExportResumable[TestResource](ctx, client, Options{
+			Filter: Filter{Sort: "field", Projection: []string{"field"}},
+		}, ResumableExportOptions{Store: store, CheckpointInterval: 2}, func(resource TestResource) error {
+			ids = append(ids, resource.ID)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"1", "2", "3"}, ids)
+		require.Equal(t, 3, progress.Documents)
+		require.Equal(t, []string{`{"lastId":"2"}`}, store.saves)
+		require.Nil(t, store.checkpoint)
+	})
+
+	t.Run("continue from the saved checkpoint", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "export").
+			AddMatcher(gock.CrudQueryMatcher(t, gock.Filter{
+				Sort: "_id",
+				MongoQuery: map[string]any{
+					"$and": []any{
+						map[string]any{"field": "v"},
+						map[string]any{"_id": map[string]any{"$gt": "2"}},
+					},
+				},
+			})).
+			Reply(200).
+			BodyString(`{"_id":"3"}`)
+
+		store := &memoryCheckpointStore{checkpoint: []byte(`{"lastId":"2"}`)}
+		ids := []string{}
+		_, err := ExportResumable[TestResource](ctx, client, Options{
+			Filter: Filter{MongoQuery: map[string]any{"field": "v"}},
+		}, ResumableExportOptions{Store: store}, func(resource TestResource) error {
+			ids = append(ids, resource.ID)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"3"}, ids)
+	})
+
+	t.Run("resume after the stream breaks", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "export").
+			Reply(200).
+			BodyString(`{"_id":"1"}` + "\n" + `{"_id":"2"}` + "\n" + `{"_id":"3`)
+		gock.NewGockScope(t, baseURL, http.MethodGet, "export").
+			AddMatcher(gock.CrudQueryMatcher(t, gock.Filter{
+				MongoQuery: map[string]any{"_id": map[string]any{"$gt": "2"}},
+			})).
+			Reply(200).
+			BodyString(`{"_id":"3"}`)
+
+		store := &memoryCheckpointStore{}
+		ids := []string{}
+		progress, err := ExportResumable[TestResource](ctx, client, Options{}, ResumableExportOptions{
+			Store:      store,
+			MaxRetries: 1,
+		}, func(resource TestResource) error {
+			ids = append(ids, resource.ID)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"1", "2", "3"}, ids)
+		require.Equal(t, 3, progress.Documents)
+		require.Equal(t, []string{`{"lastId":"2"}`}, store.saves)
+	})
+
+	t.Run("save the checkpoint when retries are exhausted", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "export").
+			Reply(200).
+			BodyString(`{"_id":"1"}` + "\n" + `{"_id":`)
+
+		store := &memoryCheckpointStore{}
+		progress, err := ExportResumable[TestResource](ctx, client, Options{}, ResumableExportOptions{Store: store}, func(resource TestResource) error {
+			return nil
+		})
+		require.Error(t, err)
+		require.Equal(t, 1, progress.Documents)
+		require.Equal(t, `{"lastId":"1"}`, string(store.checkpoint))
+	})
+
+	t.Run("do not retry callback errors", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "export").
+			Reply(200).
+			BodyString(`{"_id":"1"}` + "\n" + `{"_id":"2"}`)
+
+		expectedErr := errors.New("stop")
+		store := &memoryCheckpointStore{}
+		_, err := ExportResumable[TestResource](ctx, client, Options{}, ResumableExportOptions{
+			Store:      store,
+			MaxRetries: 3,
+		}, func(resource TestResource) error {
+			if resource.ID == "2" {
+				return expectedErr
+			}
+			return nil
+		})
+		require.ErrorIs(t, err, expectedErr)
+		require.Equal(t, `{"lastId":"1"}`, string(store.checkpoint))
+	})
+
+	t.Run("do not retry client errors", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodGet, "export").
+			Reply(400).
+			AddHeader("Content-Type", "application/json").
+			BodyString(`{"message":"bad request"}`)
+
+		_, err := ExportResumable[TestResource](ctx, client, Options{}, ResumableExportOptions{
+			Store:      &memoryCheckpointStore{},
+			MaxRetries: 3,
+		}, func(resource TestResource) error { return nil })
+		require.ErrorIs(t, err, ErrResponse)
+		require.EqualError(t, err, "bad request")
+	})
+
+	t.Run("throws with invalid options", func(t *testing.T) {
+		_, err := ExportResumable[TestResource](ctx, client, Options{}, ResumableExportOptions{}, func(resource TestResource) error { return nil })
+		require.ErrorIs(t, err, ErrCheckpoint)
+
+		_, err = ExportResumable[TestResource](ctx, client, Options{Filter: Filter{Limit: 10}}, ResumableExportOptions{
+			Store: &memoryCheckpointStore{},
+		}, func(resource TestResource) error { return nil })
+		require.ErrorIs(t, err, ErrInvalidFilter)
+
+		_, err = ExportResumable[TestResource](ctx, client, Options{}, ResumableExportOptions{
+			Store: &memoryCheckpointStore{checkpoint: []byte(`{}`)},
+		}, func(resource TestResource) error { return nil })
+		require.ErrorIs(t, err, ErrCheckpoint)
+	})
+}
@@ -0,0 +1,169 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mia-platform/go-crud-service-client/internal/types"
+)
+
+// State is the state of a document, saved by crud-service in the __STATE__ field.
+type State = types.State
+
+const (
+	// StatePublic is the state of the documents returned by default by crud-service
+	StatePublic State = "PUBLIC"
+	// StateDraft is the state of the documents not yet published
+	StateDraft State = "DRAFT"
+	// StateTrash is the state of the documents moved to the trash
+	StateTrash State = "TRASH"
+	// StateDeleted is the state of the documents removed from the trash
+	StateDeleted State = "DELETED"
+)
+
+var stateTransitions = map[State][]State{
+	StatePublic: {StateDraft},
+	StateDraft:  {StatePublic, StateTrash},
+	StateTrash:  {StateDraft, StateDeleted},
+}
+
+// CanTransition reports whether crud-service allows to move a document from
+// the state from to the state to.
+func CanTransition(from, to State) bool {
+	for _, allowed := range stateTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidState(state State) bool {
+	switch state {
+	case StatePublic, StateDraft, StateTrash, StateDeleted:
+		return true
+	}
+	return false
+}
+
+// validateStateTransition checks that stateTo is reachable from all the states
+// requested with the _st filter. If no state is requested, crud-service decides
+// which documents are eligible.
+func validateStateTransition(stateTo State, from []State) error {
+	if !isValidState(stateTo) {
+		return fmt.Errorf("%w: %s", ErrInvalidState, stateTo)
+	}
+	for _, state := range from {
+		if !CanTransition(state, stateTo) {
+			return fmt.Errorf("%w: from %s to %s", ErrInvalidStateTransition, state, stateTo)
+		}
+	}
+	return nil
+}
+
+// Metadata contains the fields that crud-service adds to every document.
+// It could be embedded in the Resource struct to read them.
+type Metadata struct {
+	CreatorID string     `json:"creatorId,omitempty"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+	UpdaterID string     `json:"updaterId,omitempty"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+	State     State      `json:"__STATE__,omitempty"`
+}
+
+func joinStates(states []State) string {
+	values := make([]string, 0, len(states))
+	for _, state := range states {
+		values = append(values, string(state))
+	}
+	return strings.Join(values, ",")
+}
+
+type changeStateByIDBody struct {
+	StateTo State `json:"stateTo"`
+}
+
+// ChangeStateByID moves the document with the specified _id to the state stateTo.
+// Returns the number of updated documents.
+func (c Client[Resource]) ChangeStateByID(ctx context.Context, id string, stateTo State, options Options) (int, error) {
+	if err := c.checkQueryPolicy(options, false); err != nil {
+		return 0, err
+	}
+	defer c.cache.invalidateID(ctx, id)
+
+	if err := validateStateTransition(stateTo, options.Filter.State); err != nil {
+		return 0, err
+	}
+
+	req, err := c.client.NewRequestWithContext(ctx, http.MethodPost, id+"/state", changeStateByIDBody{StateTo: stateTo})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := options.setOptionsInRequest(req); err != nil {
+		return 0, err
+	}
+
+	if _, err := c.client.Do(req, nil); err != nil {
+		return 0, responseError(err)
+	}
+	return 1, nil
+}
+
+type ChangeStateItem struct {
+	Filter  PatchBulkFilter `json:"filter"`
+	StateTo State           `json:"stateTo"`
+}
+type ChangeStateBody []ChangeStateItem
+
+// ChangeStateMany moves the documents matching each filter to the related state.
+// Returns the number of updated documents.
+func (c Client[Resource]) ChangeStateMany(ctx context.Context, body ChangeStateBody, options Options) (int, error) {
+	queries := make([]map[string]any, 0, len(body))
+	for _, item := range body {
+		queries = append(queries, item.Filter.MongoQuery)
+	}
+	if err := c.checkQueryPolicy(options, false, queries...); err != nil {
+		return 0, err
+	}
+	defer c.cache.invalidateAll(ctx)
+
+	for _, item := range body {
+		if err := validateStateTransition(item.StateTo, options.Filter.State); err != nil {
+			return 0, err
+		}
+	}
+
+	req, err := c.client.NewRequestWithContext(ctx, http.MethodPost, "state", body)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := options.setOptionsInRequest(req); err != nil {
+		return 0, err
+	}
+
+	var responseCount int
+	if _, err := c.client.Do(req, &responseCount); err != nil {
+		return 0, responseError(err)
+	}
+	return responseCount, nil
+}
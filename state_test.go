@@ -0,0 +1,239 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	gock "github.com/mia-platform/go-crud-service-client/testhelper/gock"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadata(t *testing.T) {
+	type resourceWithMetadata struct {
+		Metadata
+		ID    string `json:"_id"`
+		Field string `json:"field"`
+	}
+
+	t.Run("decode metadata embedded in resource", func(t *testing.T) {
+		var resource resourceWithMetadata
+		err := json.Unmarshal([]byte(`{
+			"_id": "my-id",
+			"field": "v-1",
+			"creatorId": "user-1",
+			"createdAt": "2023-01-02T10:00:00.000Z",
+			"updaterId": "user-2",
+			"updatedAt": "2023-01-03T10:00:00.000Z",
+			"__STATE__": "DRAFT"
+		}`), &resource)
+		require.NoError(t, err)
+
+		createdAt := time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC)
+		updatedAt := time.Date(2023, 1, 3, 10, 0, 0, 0, time.UTC)
+		require.Equal(t, resourceWithMetadata{
+			Metadata: Metadata{
+				CreatorID: "user-1",
+				CreatedAt: &createdAt,
+				UpdaterID: "user-2",
+				UpdatedAt: &updatedAt,
+				State:     StateDraft,
+			},
+			ID:    "my-id",
+			Field: "v-1",
+		}, resource)
+	})
+
+	t.Run("encode only state when metadata are empty", func(t *testing.T) {
+		resource := resourceWithMetadata{
+			Metadata: Metadata{State: StateDraft},
+			ID:       "my-id",
+			Field:    "v-1",
+		}
+
+		data, err := json.Marshal(resource)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"_id":"my-id","field":"v-1","__STATE__":"DRAFT"}`, string(data))
+	})
+}
+
+func TestCanTransition(t *testing.T) {
+	require.True(t, CanTransition(StatePublic, StateDraft))
+	require.True(t, CanTransition(StateDraft, StatePublic))
+	require.True(t, CanTransition(StateDraft, StateTrash))
+	require.True(t, CanTransition(StateTrash, StateDraft))
+	require.True(t, CanTransition(StateTrash, StateDeleted))
+
+	require.False(t, CanTransition(StatePublic, StateDeleted))
+	require.False(t, CanTransition(StateDeleted, StateTrash))
+	require.False(t, CanTransition(StateDraft, StateDraft))
+}
+
+func TestChangeStateByID(t *testing.T) {
+	ctx := context.Background()
+	client := getClient(t)
+
+	id := "my-id-1"
+
+	t.Run("change state", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodPost, id+"/state").
+			JSON(map[string]any{"stateTo": "TRASH"}).
+			Reply(204)
+
+		n, err := client.ChangeStateByID(ctx, id, StateTrash, Options{})
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+	})
+
+	t.Run("change state with filter", func(t *testing.T) {
+		filter := Filter{
+			Fields: map[string]string{"field": "v-1"},
+			State:  []State{StateTrash},
+		}
+
+		gock.NewGockScope(t, baseURL, http.MethodPost, id+"/state").
+			AddMatcher(gock.CrudQueryMatcher(t, gock.Filter(filter))).
+			JSON(map[string]any{"stateTo": "DRAFT"}).
+			Reply(204)
+
+		n, err := client.ChangeStateByID(ctx, id, StateDraft, Options{Filter: filter})
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+	})
+
+	t.Run("throws - invalid state", func(t *testing.T) {
+		n, err := client.ChangeStateByID(ctx, id, State("UNKNOWN"), Options{})
+		require.ErrorIs(t, err, ErrInvalidState)
+		require.Equal(t, 0, n)
+	})
+
+	t.Run("throws - transition not allowed", func(t *testing.T) {
+		n, err := client.ChangeStateByID(ctx, id, StateDeleted, Options{
+			Filter: Filter{State: []State{StatePublic}},
+		})
+		require.ErrorIs(t, err, ErrInvalidStateTransition)
+		require.EqualError(t, err, "invalid state transition: from PUBLIC to DELETED")
+		require.Equal(t, 0, n)
+	})
+
+	t.Run("throws - not found", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodPost, id+"/state").
+			Reply(404).
+			JSON(CrudErrorResponse{
+				Message:    "element not found",
+				StatusCode: 404,
+				Error:      "Not Found",
+			})
+
+		n, err := client.ChangeStateByID(ctx, id, StateTrash, Options{})
+		require.EqualError(t, err, "element not found")
+		require.Equal(t, 0, n)
+	})
+
+	t.Run("proxy headers in request", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodPost, id+"/state").
+			MatchHeaders(map[string]string{
+				"foo": "bar",
+				"taz": "ok",
+			}).
+			Reply(204)
+
+		h := http.Header{}
+		h.Set("foo", "bar")
+		h.Set("taz", "ok")
+
+		n, err := client.ChangeStateByID(ctx, id, StateTrash, Options{Headers: h})
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+	})
+}
+
+func TestChangeStateMany(t *testing.T) {
+	ctx := context.Background()
+	client := getClient(t)
+
+	body := ChangeStateBody{
+		{
+			Filter: PatchBulkFilter{
+				Fields: map[string]string{"field": "v-1"},
+			},
+			StateTo: StateTrash,
+		},
+		{
+			Filter: PatchBulkFilter{
+				MongoQuery: map[string]any{"intField": map[string]any{"$gt": 3}},
+			},
+			StateTo: StateDraft,
+		},
+	}
+	expectedBody := `[{"filter":{"field":"v-1"},"stateTo":"TRASH"},{"filter":{"_q":"{\"intField\":{\"$gt\":3}}"},"stateTo":"DRAFT"}]`
+
+	t.Run("change state", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodPost, "state").
+			BodyString(expectedBody).
+			Reply(200).
+			JSON(3)
+
+		n, err := client.ChangeStateMany(ctx, body, Options{})
+		require.NoError(t, err)
+		require.Equal(t, 3, n)
+	})
+
+	t.Run("throws - transition not allowed", func(t *testing.T) {
+		n, err := client.ChangeStateMany(ctx, body, Options{
+			Filter: Filter{State: []State{StatePublic}},
+		})
+		require.ErrorIs(t, err, ErrInvalidStateTransition)
+		require.Equal(t, 0, n)
+	})
+
+	t.Run("throws - crud error", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodPost, "state").
+			Reply(400).
+			JSON(CrudErrorResponse{
+				Message:    "bad request",
+				StatusCode: 400,
+				Error:      "Bad Request",
+			})
+
+		n, err := client.ChangeStateMany(ctx, body, Options{})
+		require.EqualError(t, err, "bad request")
+		require.Equal(t, 0, n)
+	})
+
+	t.Run("proxy headers in request", func(t *testing.T) {
+		gock.NewGockScope(t, baseURL, http.MethodPost, "state").
+			MatchHeaders(map[string]string{
+				"foo": "bar",
+				"taz": "ok",
+			}).
+			Reply(200).
+			JSON(2)
+
+		h := http.Header{}
+		h.Set("foo", "bar")
+		h.Set("taz", "ok")
+
+		n, err := client.ChangeStateMany(ctx, body, Options{Headers: h})
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+	})
+}
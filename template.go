@@ -0,0 +1,339 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	templateParamRegex       = regexp.MustCompile(`^:([A-Za-z_][A-Za-z0-9_]*)$`)
+	templateInlineParamRegex = regexp.MustCompile(`:([A-Za-z_][A-Za-z0-9_]*)`)
+
+	// templateCodeOperators run their value as code on the server, so they can not
+	// contain placeholders.
+	templateCodeOperators = map[string]bool{
+		"$where":       true,
+		"$function":    true,
+		"$accumulator": true,
+		"$expr":        true,
+	}
+)
+
+// QueryTemplate is a MongoQuery with named placeholders, bound to values with Bind.
+//
+// A string value equal to `:name` is replaced by the value of the param `name`. In the
+// value of `$regex`, the placeholders can be part of the string (e.g. `^:prefix`) and
+// are replaced by the value with the regex metacharacters escaped. E.g.
+//
+//	{"owner": ":user", "name": {"$regex": "^:prefix"}}
+//
+// The bound values can not add operators to the query: objects with keys starting
+// with `$` are rejected, and placeholders are not allowed under the operators that
+// run code on the server, like `$where` and `$expr`.
+type QueryTemplate struct {
+	name   string
+	root   templateNode
+	params []string
+}
+
+type templateNode interface {
+	bind(values map[string]any) (any, error)
+}
+
+// NewQueryTemplate compiles the query to a template.
+func NewQueryTemplate(name string, query map[string]any) (*QueryTemplate, error) {
+	params := map[string]bool{}
+	root, err := compileTemplateNode(query, false, false, params)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %s", ErrInvalidTemplate, name, err)
+	}
+
+	names := make([]string, 0, len(params))
+	for param := range params {
+		names = append(names, param)
+	}
+	sort.Strings(names)
+
+	return &QueryTemplate{name: name, root: root, params: names}, nil
+}
+
+// ParseQueryTemplate compiles the JSON query to a template.
+func ParseQueryTemplate(name string, query string) (*QueryTemplate, error) {
+	var parsed map[string]any
+	decoder := json.NewDecoder(bytes.NewReader([]byte(query)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("%w %s: %s", ErrInvalidTemplate, name, err)
+	}
+	return NewQueryTemplate(name, parsed)
+}
+
+// Name returns the name of the template.
+func (t *QueryTemplate) Name() string {
+	return t.name
+}
+
+// Params returns the sorted names of the params of the template.
+func (t *QueryTemplate) Params() []string {
+	return append([]string{}, t.params...)
+}
+
+// Bind returns the MongoQuery with the placeholders replaced by the values.
+// All the params must have a value, and no other value is accepted.
+func (t *QueryTemplate) Bind(values map[string]any) (map[string]any, error) {
+	for _, param := range t.params {
+		if _, ok := values[param]; !ok {
+			return nil, fmt.Errorf("%w %s: missing value for param %s", ErrTemplateBinding, t.name, param)
+		}
+	}
+	for _, param := range sortedKeys(values) {
+		if !contains(t.params, param) {
+			return nil, fmt.Errorf("%w %s: unknown param %s", ErrTemplateBinding, t.name, param)
+		}
+	}
+
+	bound, err := t.root.bind(values)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %s", ErrTemplateBinding, t.name, err)
+	}
+	return bound.(map[string]any), nil
+}
+
+func contains(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func compileTemplateNode(value any, isRegex, isCode bool, params map[string]bool) (templateNode, error) {
+	switch v := value.(type) {
+	case string:
+		if isRegex {
+			matches := regexTemplateParams(v)
+			if isCode && len(matches) > 0 {
+				return nil, fmt.Errorf("placeholder not allowed in code operators")
+			}
+			for _, match := range matches {
+				params[v[match[2]:match[3]]] = true
+			}
+			return regexTemplateNode(v), nil
+		}
+		if match := templateParamRegex.FindStringSubmatch(v); match != nil {
+			if isCode {
+				return nil, fmt.Errorf("placeholder %s not allowed in code operators", v)
+			}
+			params[match[1]] = true
+			return paramTemplateNode(match[1]), nil
+		}
+		return literalTemplateNode{value: v}, nil
+	case map[string]any:
+		node := mapTemplateNode{}
+		for key, item := range v {
+			if templateParamRegex.MatchString(key) {
+				return nil, fmt.Errorf("placeholder not allowed in key %s", key)
+			}
+			child, err := compileTemplateNode(item, key == "$regex", isCode || templateCodeOperators[key], params)
+			if err != nil {
+				return nil, err
+			}
+			node[key] = child
+		}
+		return node, nil
+	case []any:
+		node := make(arrayTemplateNode, 0, len(v))
+		for _, item := range v {
+			child, err := compileTemplateNode(item, false, isCode, params)
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, child)
+		}
+		return node, nil
+	default:
+		return literalTemplateNode{value: v}, nil
+	}
+}
+
+type literalTemplateNode struct {
+	value any
+}
+
+func (n literalTemplateNode) bind(map[string]any) (any, error) {
+	return n.value, nil
+}
+
+type mapTemplateNode map[string]templateNode
+
+func (n mapTemplateNode) bind(values map[string]any) (any, error) {
+	bound := make(map[string]any, len(n))
+	for key, child := range n {
+		value, err := child.bind(values)
+		if err != nil {
+			return nil, err
+		}
+		bound[key] = value
+	}
+	return bound, nil
+}
+
+type arrayTemplateNode []templateNode
+
+func (n arrayTemplateNode) bind(values map[string]any) (any, error) {
+	bound := make([]any, 0, len(n))
+	for _, child := range n {
+		value, err := child.bind(values)
+		if err != nil {
+			return nil, err
+		}
+		bound = append(bound, value)
+	}
+	return bound, nil
+}
+
+type paramTemplateNode string
+
+func (n paramTemplateNode) bind(values map[string]any) (any, error) {
+	return sanitizeTemplateValue(string(n), values[string(n)])
+}
+
+type regexTemplateNode string
+
+func (n regexTemplateNode) bind(values map[string]any) (any, error) {
+	pattern := string(n)
+
+	var bound strings.Builder
+	last := 0
+	for _, match := range regexTemplateParams(pattern) {
+		name := pattern[match[2]:match[3]]
+		value, ok := values[name].(string)
+		if !ok {
+			return nil, fmt.Errorf("param %s used in $regex must be a string", name)
+		}
+		bound.WriteString(pattern[last:match[0]])
+		bound.WriteString(regexp.QuoteMeta(value))
+		last = match[1]
+	}
+	bound.WriteString(pattern[last:])
+	return bound.String(), nil
+}
+
+// regexTemplateParams returns the indexes of the placeholders in a regex, as returned
+// by FindAllStringSubmatchIndex. The placeholders can not follow `?`, to keep groups
+// like `(?:a)`, nor be inside a character class, to keep classes like `[[:alpha:]]`,
+// while adjacent placeholders like `:a:b` are both found.
+func regexTemplateParams(pattern string) [][]int {
+	inClass := regexCharacterClasses(pattern)
+	matches := [][]int{}
+	for _, match := range templateInlineParamRegex.FindAllStringSubmatchIndex(pattern, -1) {
+		if match[0] > 0 && pattern[match[0]-1] == '?' || inClass[match[0]] {
+			continue
+		}
+		matches = append(matches, match)
+	}
+	return matches
+}
+
+// regexCharacterClasses reports for each byte of the pattern whether it is inside
+// a character class like `[a-z:]`, including its brackets.
+func regexCharacterClasses(pattern string) []bool {
+	inClass := make([]bool, len(pattern))
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '[':
+			start := i
+			i++
+			if i < len(pattern) && pattern[i] == '^' {
+				i++
+			}
+			// a `]` at the start of the class is a literal
+			if i < len(pattern) && pattern[i] == ']' {
+				i++
+			}
+			for ; i < len(pattern) && pattern[i] != ']'; i++ {
+				switch {
+				case pattern[i] == '\\':
+					i++
+				case strings.HasPrefix(pattern[i:], "[:"):
+					if end := strings.Index(pattern[i+2:], ":]"); end >= 0 {
+						i += end + 3
+					}
+				}
+			}
+			for j := start; j <= i && j < len(pattern); j++ {
+				inClass[j] = true
+			}
+		}
+	}
+	return inClass
+}
+
+// sanitizeTemplateValue converts the value to its JSON representation, rejecting
+// the objects with keys starting with `$`.
+func sanitizeTemplateValue(param string, value any) (any, error) {
+	switch value.(type) {
+	case nil, string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64,
+		float32, float64, json.Number, time.Time, Date, ObjectID:
+		return value, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("param %s: %s", param, err)
+	}
+	var sanitized any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&sanitized); err != nil {
+		return nil, fmt.Errorf("param %s: %s", param, err)
+	}
+	if key, found := findOperatorKey(sanitized); found {
+		return nil, fmt.Errorf("param %s: key %s not allowed", param, key)
+	}
+	return sanitized, nil
+}
+
+func findOperatorKey(value any) (string, bool) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if strings.HasPrefix(key, "$") {
+				return key, true
+			}
+			if found, ok := findOperatorKey(item); ok {
+				return found, true
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if found, ok := findOperatorKey(item); ok {
+				return found, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,174 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryTemplate(t *testing.T) {
+	template, err := ParseQueryTemplate("by-owner", `{
+		"owner": ":user",
+		"name": {"$regex": "^:prefix(?:_draft)?", "$options": "i"},
+		"status": {"$in": [":status", "archived"]},
+		"age": {"$gt": 18},
+		"url": "http://example.com"
+	}`)
+	require.NoError(t, err)
+	require.Equal(t, "by-owner", template.Name())
+	require.Equal(t, []string{"prefix", "status", "user"}, template.Params())
+
+	t.Run("bind values", func(t *testing.T) {
+		query, err := template.Bind(map[string]any{
+			"user":   "user-1",
+			"prefix": "a.b*",
+			"status": "active",
+		})
+		require.NoError(t, err)
+		require.Equal(t, map[string]any{
+			"owner":  "user-1",
+			"name":   map[string]any{"$regex": `^a\.b\*(?:_draft)?`, "$options": "i"},
+			"status": map[string]any{"$in": []any{"active", "archived"}},
+			"age":    map[string]any{"$gt": json.Number("18")},
+			"url":    "http://example.com",
+		}, query)
+	})
+
+	t.Run("bind objects without operators", func(t *testing.T) {
+		query, err := template.Bind(map[string]any{
+			"user":   map[string]any{"id": "user-1"},
+			"prefix": "",
+			"status": []string{"a"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, map[string]any{"id": "user-1"}, query["owner"])
+		require.Equal(t, map[string]any{"$regex": `^(?:_draft)?`, "$options": "i"}, query["name"])
+	})
+
+	t.Run("the template is not modified by binding", func(t *testing.T) {
+		first, err := template.Bind(map[string]any{"user": "a", "prefix": "b", "status": "c"})
+		require.NoError(t, err)
+		second, err := template.Bind(map[string]any{"user": "x", "prefix": "y", "status": "z"})
+		require.NoError(t, err)
+		require.Equal(t, "a", first["owner"])
+		require.Equal(t, "x", second["owner"])
+	})
+
+	tests := []struct {
+		name          string
+		values        map[string]any
+		expectedError string
+	}{
+		{
+			name:          "missing value",
+			values:        map[string]any{"user": "a", "prefix": "b"},
+			expectedError: "query template binding failed by-owner: missing value for param status",
+		},
+		{
+			name:          "unknown param",
+			values:        map[string]any{"user": "a", "prefix": "b", "status": "c", "other": "d"},
+			expectedError: "query template binding failed by-owner: unknown param other",
+		},
+		{
+			name:          "operator in value",
+			values:        map[string]any{"user": map[string]any{"$ne": nil}, "prefix": "b", "status": "c"},
+			expectedError: "query template binding failed by-owner: param user: key $ne not allowed",
+		},
+		{
+			name:          "nested operator in value",
+			values:        map[string]any{"user": "a", "prefix": "b", "status": []any{map[string]any{"a": map[string]any{"$where": "1"}}}},
+			expectedError: "query template binding failed by-owner: param status: key $where not allowed",
+		},
+		{
+			name:          "non string value in regex",
+			values:        map[string]any{"user": "a", "prefix": 3, "status": "c"},
+			expectedError: "query template binding failed by-owner: param prefix used in $regex must be a string",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, err := template.Bind(test.values)
+			require.ErrorIs(t, err, ErrTemplateBinding)
+			require.EqualError(t, err, test.expectedError)
+			require.Nil(t, query)
+		})
+	}
+}
+
+func TestNewQueryTemplate(t *testing.T) {
+	t.Run("template without params", func(t *testing.T) {
+		template, err := NewQueryTemplate("static", map[string]any{"a": 1})
+		require.NoError(t, err)
+		require.Empty(t, template.Params())
+
+		query, err := template.Bind(nil)
+		require.NoError(t, err)
+		require.Equal(t, map[string]any{"a": 1}, query)
+	})
+
+	t.Run("placeholder in key", func(t *testing.T) {
+		_, err := NewQueryTemplate("invalid", map[string]any{":field": 1})
+		require.ErrorIs(t, err, ErrInvalidTemplate)
+		require.EqualError(t, err, "invalid query template invalid: placeholder not allowed in key :field")
+	})
+
+	t.Run("placeholder in code operators", func(t *testing.T) {
+		queries := []map[string]any{
+			{"$where": ":code"},
+			{"$expr": map[string]any{"$eq": []any{"$a", ":value"}}},
+			{"a": map[string]any{"$function": map[string]any{"body": ":code", "args": []any{}, "lang": "js"}}},
+			{"$expr": map[string]any{"$regexMatch": map[string]any{"input": "$a", "regex": "^b"}}, "b": map[string]any{"$accumulator": map[string]any{"init": []any{":code"}}}},
+		}
+		for _, query := range queries {
+			_, err := NewQueryTemplate("code", query)
+			require.ErrorIs(t, err, ErrInvalidTemplate)
+			require.ErrorContains(t, err, "not allowed in code operators")
+		}
+
+		template, err := NewQueryTemplate("code", map[string]any{"$where": "this.a == 1", "b": ":value"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"value"}, template.Params())
+	})
+
+	t.Run("adjacent placeholders in regex", func(t *testing.T) {
+		template, err := NewQueryTemplate("adjacent", map[string]any{"a": map[string]any{"$regex": "^:a:b(?:c)"}})
+		require.NoError(t, err)
+		require.Equal(t, []string{"a", "b"}, template.Params())
+
+		query, err := template.Bind(map[string]any{"a": "x.", "b": "y*"})
+		require.NoError(t, err)
+		require.Equal(t, map[string]any{"a": map[string]any{"$regex": `^x\.y\*(?:c)`}}, query)
+	})
+
+	t.Run("colons in character classes of regex", func(t *testing.T) {
+		template, err := NewQueryTemplate("classes", map[string]any{"a": map[string]any{"$regex": `^[[:alpha:]]+:p[^:q\]:r]:s`}})
+		require.NoError(t, err)
+		require.Equal(t, []string{"p", "s"}, template.Params())
+
+		query, err := template.Bind(map[string]any{"p": "x", "s": "y"})
+		require.NoError(t, err)
+		require.Equal(t, map[string]any{"a": map[string]any{"$regex": `^[[:alpha:]]+x[^:q\]:r]y`}}, query)
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		_, err := ParseQueryTemplate("invalid", `{"a":`)
+		require.ErrorIs(t, err, ErrInvalidTemplate)
+	})
+}
@@ -91,6 +91,19 @@ func CrudQueryMatcher(t *testing.T, expectedFilter Filter) gock.MatchFunc {
 			}
 		}
 
+		if expectedFilter.State != nil {
+			actualState := actualQuery.Get("_st")
+
+			expectedStates := make([]string, 0, len(expectedFilter.State))
+			for _, state := range expectedFilter.State {
+				expectedStates = append(expectedStates, string(state))
+			}
+
+			if !assert.Equal(t, strings.Join(expectedStates, ","), actualState) {
+				return false, fmt.Errorf("state query check fails. Actual: %s, required: %s", actualState, expectedFilter.State)
+			}
+		}
+
 		return true, nil
 	}
 }
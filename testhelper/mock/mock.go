@@ -2,6 +2,7 @@ package mock
 
 import (
 	"context"
+	"io"
 
 	"github.com/mia-platform/go-crud-service-client"
 )
@@ -11,6 +12,11 @@ type CRUD[Resource any] struct {
 	GetByIDError         error
 	GetByIDAssertionFunc func(ctx context.Context, id string, options crud.Options)
 
+	GetByIDsResult        []Resource
+	GetByIDsNotFound      []string
+	GetByIDsError         error
+	GetByIDsAssertionFunc func(ctx context.Context, ids []string, options crud.Options)
+
 	ListResult        []Resource
 	ListError         error
 	ListAssertionFunc func(ctx context.Context, options crud.Options)
@@ -19,10 +25,34 @@ type CRUD[Resource any] struct {
 	CountError         error
 	CountAssertionFunc func(ctx context.Context, options crud.Options)
 
+	ListPageResult        crud.Page[Resource]
+	ListPageError         error
+	ListPageAssertionFunc func(ctx context.Context, options crud.Options)
+
+	FindOneResult        *Resource
+	FindOneError         error
+	FindOneAssertionFunc func(ctx context.Context, options crud.Options)
+
+	ExistsResult        bool
+	ExistsError         error
+	ExistsAssertionFunc func(ctx context.Context, options crud.Options)
+
+	ExistsByIDResult        bool
+	ExistsByIDError         error
+	ExistsByIDAssertionFunc func(ctx context.Context, id string, options crud.Options)
+
 	ExportResult        []Resource
 	ExportError         error
 	ExportAssertionFunc func(ctx context.Context, options crud.Options)
 
+	ExportStreamResult        []Resource
+	ExportStreamError         error
+	ExportStreamAssertionFunc func(ctx context.Context, options crud.Options)
+
+	ExportToResult        []byte
+	ExportToError         error
+	ExportToAssertionFunc func(ctx context.Context, format crud.ExportFormat, options crud.Options)
+
 	PatchResult        *Resource
 	PatchError         error
 	PatchAssertionFunc func(ctx context.Context, id string, body crud.PatchBody, options crud.Options)
@@ -53,6 +83,14 @@ type CRUD[Resource any] struct {
 	UpsertOneResult        *Resource
 	UpsertOneError         error
 	UpsertOneAssertionFunc func(ctx context.Context, body crud.UpsertBody, options crud.Options)
+
+	ChangeStateByIDResult        int
+	ChangeStateByIDError         error
+	ChangeStateByIDAssertionFunc func(ctx context.Context, id string, stateTo crud.State, options crud.Options)
+
+	ChangeStateManyResult        int
+	ChangeStateManyError         error
+	ChangeStateManyAssertionFunc func(ctx context.Context, body crud.ChangeStateBody, options crud.Options)
 }
 
 func (c *CRUD[Resource]) GetByID(ctx context.Context, id string, options crud.Options) (*Resource, error) {
@@ -62,6 +100,13 @@ func (c *CRUD[Resource]) GetByID(ctx context.Context, id string, options crud.Op
 	return c.GetByIDResult, c.GetByIDError
 }
 
+func (c *CRUD[Resource]) GetByIDs(ctx context.Context, ids []string, options crud.Options) ([]Resource, []string, error) {
+	if c.GetByIDsAssertionFunc != nil {
+		c.GetByIDsAssertionFunc(ctx, ids, options)
+	}
+	return c.GetByIDsResult, c.GetByIDsNotFound, c.GetByIDsError
+}
+
 func (c *CRUD[Resource]) List(ctx context.Context, options crud.Options) ([]Resource, error) {
 	if c.ListAssertionFunc != nil {
 		c.ListAssertionFunc(ctx, options)
@@ -76,6 +121,34 @@ func (c *CRUD[Resource]) Count(ctx context.Context, options crud.Options) (int,
 	return c.CountResult, c.CountError
 }
 
+func (c *CRUD[Resource]) ListPage(ctx context.Context, options crud.Options) (crud.Page[Resource], error) {
+	if c.ListPageAssertionFunc != nil {
+		c.ListPageAssertionFunc(ctx, options)
+	}
+	return c.ListPageResult, c.ListPageError
+}
+
+func (c *CRUD[Resource]) FindOne(ctx context.Context, options crud.Options) (*Resource, error) {
+	if c.FindOneAssertionFunc != nil {
+		c.FindOneAssertionFunc(ctx, options)
+	}
+	return c.FindOneResult, c.FindOneError
+}
+
+func (c *CRUD[Resource]) Exists(ctx context.Context, options crud.Options) (bool, error) {
+	if c.ExistsAssertionFunc != nil {
+		c.ExistsAssertionFunc(ctx, options)
+	}
+	return c.ExistsResult, c.ExistsError
+}
+
+func (c *CRUD[Resource]) ExistsByID(ctx context.Context, id string, options crud.Options) (bool, error) {
+	if c.ExistsByIDAssertionFunc != nil {
+		c.ExistsByIDAssertionFunc(ctx, id, options)
+	}
+	return c.ExistsByIDResult, c.ExistsByIDError
+}
+
 func (c *CRUD[Resource]) Export(ctx context.Context, options crud.Options) ([]Resource, error) {
 	if c.ExportAssertionFunc != nil {
 		c.ExportAssertionFunc(ctx, options)
@@ -83,6 +156,31 @@ func (c *CRUD[Resource]) Export(ctx context.Context, options crud.Options) ([]Re
 	return c.ExportResult, c.ExportError
 }
 
+func (c *CRUD[Resource]) ExportStream(ctx context.Context, options crud.Options, fn func(resource Resource) error) (crud.ExportProgress, error) {
+	if c.ExportStreamAssertionFunc != nil {
+		c.ExportStreamAssertionFunc(ctx, options)
+	}
+	progress := crud.ExportProgress{}
+	for _, resource := range c.ExportStreamResult {
+		if err := fn(resource); err != nil {
+			return progress, err
+		}
+		progress.Documents++
+	}
+	return progress, c.ExportStreamError
+}
+
+func (c *CRUD[Resource]) ExportTo(ctx context.Context, w io.Writer, format crud.ExportFormat, options crud.Options) (int64, error) {
+	if c.ExportToAssertionFunc != nil {
+		c.ExportToAssertionFunc(ctx, format, options)
+	}
+	n, err := w.Write(c.ExportToResult)
+	if err != nil {
+		return int64(n), err
+	}
+	return int64(n), c.ExportToError
+}
+
 func (c *CRUD[Resource]) PatchById(ctx context.Context, id string, body crud.PatchBody, options crud.Options) (*Resource, error) {
 	if c.PatchAssertionFunc != nil {
 		c.PatchAssertionFunc(ctx, id, body, options)
@@ -140,3 +238,17 @@ func (c *CRUD[Resource]) UpsertOne(ctx context.Context, body crud.UpsertBody, op
 	}
 	return c.UpsertOneResult, c.UpsertOneError
 }
+
+func (c *CRUD[Resource]) ChangeStateByID(ctx context.Context, id string, stateTo crud.State, options crud.Options) (int, error) {
+	if c.ChangeStateByIDAssertionFunc != nil {
+		c.ChangeStateByIDAssertionFunc(ctx, id, stateTo, options)
+	}
+	return c.ChangeStateByIDResult, c.ChangeStateByIDError
+}
+
+func (c *CRUD[Resource]) ChangeStateMany(ctx context.Context, body crud.ChangeStateBody, options crud.Options) (int, error) {
+	if c.ChangeStateManyAssertionFunc != nil {
+		c.ChangeStateManyAssertionFunc(ctx, body, options)
+	}
+	return c.ChangeStateManyResult, c.ChangeStateManyError
+}
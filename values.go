@@ -0,0 +1,125 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// dateLayout is the layout of JavaScript Date.toISOString(), which crud-service
+// casts to a date when the field is of type Date.
+const dateLayout = "2006-01-02T15:04:05.000Z"
+
+// Date is a date to use in a MongoQuery. It is encoded in UTC with millisecond precision.
+// A time.Time in the MongoQuery is encoded in the same way.
+type Date time.Time
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Time(d).UTC().Format(dateLayout))
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var t time.Time
+	if err := json.Unmarshal(data, &t); err != nil {
+		return err
+	}
+	*d = Date(t)
+	return nil
+}
+
+// ObjectID is the hex representation of a MongoDB ObjectId. crud-service casts it
+// to an ObjectId when the field is of type ObjectId, as the _id.
+type ObjectID string
+
+// NewObjectID validates the hex representation of an ObjectId.
+func NewObjectID(id string) (ObjectID, error) {
+	objectID := ObjectID(strings.ToLower(id))
+	if !objectID.IsValid() {
+		return "", fmt.Errorf("%w: %q", ErrInvalidObjectID, id)
+	}
+	return objectID, nil
+}
+
+// IsValid reports whether the id is the hex representation of 12 bytes.
+func (id ObjectID) IsValid() bool {
+	if len(id) != 24 {
+		return false
+	}
+	_, err := hex.DecodeString(string(id))
+	return err == nil
+}
+
+func (id ObjectID) MarshalJSON() ([]byte, error) {
+	if !id.IsValid() {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidObjectID, string(id))
+	}
+	return json.Marshal(strings.ToLower(string(id)))
+}
+
+// Binary is a binary value to use in a MongoQuery. It is encoded in base64.
+type Binary []byte
+
+func (b Binary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.StdEncoding.EncodeToString(b))
+}
+
+// encodeQueryValues returns a copy of the value where the types that crud-service
+// expects in a particular format are replaced by their wrappers, e.g. time.Time by Date.
+func encodeQueryValues(value any) any {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		return Date(v)
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		return Date(*v)
+	case json.Marshaler:
+		return v
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return value
+		}
+		encoded := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			encoded[iter.Key().String()] = encodeQueryValues(iter.Value().Interface())
+		}
+		return encoded
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return value
+		}
+		encoded := make([]any, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			encoded[i] = encodeQueryValues(rv.Index(i).Interface())
+		}
+		return encoded
+	}
+	return value
+}
@@ -0,0 +1,120 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	gock "github.com/mia-platform/go-crud-service-client/testhelper/gock"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDate(t *testing.T) {
+	rome, err := time.LoadLocation("Europe/Rome")
+	require.NoError(t, err)
+	date := time.Date(2023, 5, 4, 12, 30, 15, 123456789, rome)
+
+	t.Run("marshal in UTC with milliseconds", func(t *testing.T) {
+		data, err := json.Marshal(Date(date))
+		require.NoError(t, err)
+		require.Equal(t, `"2023-05-04T10:30:15.123Z"`, string(data))
+	})
+
+	t.Run("unmarshal", func(t *testing.T) {
+		var decoded Date
+		require.NoError(t, json.Unmarshal([]byte(`"2023-05-04T10:30:15.123Z"`), &decoded))
+		require.True(t, time.Date(2023, 5, 4, 10, 30, 15, 123000000, time.UTC).Equal(time.Time(decoded)))
+	})
+}
+
+func TestObjectID(t *testing.T) {
+	t.Run("valid id is lowercased", func(t *testing.T) {
+		id, err := NewObjectID("5F1E2D3C4B5A697887960A0B")
+		require.NoError(t, err)
+		require.Equal(t, ObjectID("5f1e2d3c4b5a697887960a0b"), id)
+
+		data, err := json.Marshal(id)
+		require.NoError(t, err)
+		require.Equal(t, `"5f1e2d3c4b5a697887960a0b"`, string(data))
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		for _, invalid := range []string{"", "abc", "5f1e2d3c4b5a697887960a0z", "5f1e2d3c4b5a697887960a0b00"} {
+			_, err := NewObjectID(invalid)
+			require.ErrorIs(t, err, ErrInvalidObjectID)
+		}
+
+		_, err := json.Marshal(ObjectID("not-an-id"))
+		require.ErrorIs(t, err, ErrInvalidObjectID)
+	})
+}
+
+func TestBinary(t *testing.T) {
+	data, err := json.Marshal(Binary("hello"))
+	require.NoError(t, err)
+	require.Equal(t, `"aGVsbG8="`, string(data))
+}
+
+func TestEncodeQueryValues(t *testing.T) {
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 2, 1, 0, 0, 0, 0, time.FixedZone("CET", 3600))
+
+	query := map[string]any{
+		"createdAt": map[string]any{"$gte": from, "$lt": &to},
+		"_id":       map[string]any{"$in": []ObjectID{"5f1e2d3c4b5a697887960a0b"}},
+		"days":      map[string]any{"$in": []time.Time{from}},
+		"hash":      Binary{0x01, 0x02},
+		"nested":    map[string]string{"a": "b"},
+		"count":     3,
+		"missing":   nil,
+	}
+
+	queryBytes, err := json.Marshal(encodeQueryValues(query))
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"createdAt": {"$gte": "2023-01-01T00:00:00.000Z", "$lt": "2023-01-31T23:00:00.000Z"},
+		"_id": {"$in": ["5f1e2d3c4b5a697887960a0b"]},
+		"days": {"$in": ["2023-01-01T00:00:00.000Z"]},
+		"hash": "AQI=",
+		"nested": {"a": "b"},
+		"count": 3,
+		"missing": null
+	}`, string(queryBytes))
+}
+
+func TestListWithDateQuery(t *testing.T) {
+	client := getClient(t)
+
+	gock.NewGockScope(t, baseURL, http.MethodGet, "").
+		MatchParam("_q", `^\{"updatedAt":\{"\$gt":"2023-01-01T10:00:00\.000Z"\}\}$`).
+		Reply(200).
+		JSON([]TestResource{})
+
+	resources, err := client.List(context.Background(), Options{
+		Filter: Filter{
+			MongoQuery: map[string]any{
+				"updatedAt": map[string]any{"$gt": time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []TestResource{}, resources)
+}
@@ -0,0 +1,384 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultWatchInterval is the time between two polls of a Watcher if not specified.
+const DefaultWatchInterval = 5 * time.Second
+
+// ChangeType is the type of a Change.
+type ChangeType string
+
+const (
+	// ChangeCreated is a resource created after the high-water mark
+	ChangeCreated ChangeType = "created"
+	// ChangeUpdated is a resource updated after the high-water mark
+	ChangeUpdated ChangeType = "updated"
+	// ChangeDeleted is a resource moved to the trash or removed
+	ChangeDeleted ChangeType = "deleted"
+)
+
+// Change is a change of a resource detected by a Watcher.
+type Change[Resource any] struct {
+	Type ChangeType
+	ID   string
+	// Resource is the changed resource. It is nil for the deletions detected by
+	// DeletionIDDiff, since the resource does not exist anymore.
+	Resource *Resource
+}
+
+// DeletionStrategy is how a Watcher detects the deleted resources.
+type DeletionStrategy int
+
+const (
+	// DeletionNone does not detect deletions
+	DeletionNone DeletionStrategy = iota
+	// DeletionTrashState watches also the resources in the TRASH and DELETED states,
+	// emitting them as deleted. It is for collections where resources are
+	// deleted by changing their state.
+	DeletionTrashState
+	// DeletionIDDiff periodically exports the ids of the resources, emitting as
+	// deleted the ids that are not exported anymore. The first export is the baseline,
+	// so the deletions while the watcher is not running are not detected.
+	DeletionIDDiff
+)
+
+// WatchOptions configures a Watcher.
+type WatchOptions struct {
+	// Interval is the time between two polls. Default is DefaultWatchInterval.
+	Interval time.Duration
+	// Jitter randomizes each interval up to the given fraction, e.g. 0.1 is ±10%,
+	// so that many watchers do not poll at the same instant.
+	Jitter float64
+	// PageSize is the number of resources listed by each request. Default is DefaultPageSize.
+	PageSize int
+	// Store, if set, persists the high-water mark, so that a watcher that is restarted
+	// continues from there.
+	Store CheckpointStore
+	// Since is the high-water mark if there is no checkpoint. If zero, all the
+	// resources are emitted at the first poll.
+	Since time.Time
+	// Deletion is how deleted resources are detected. Default is DeletionNone.
+	Deletion DeletionStrategy
+	// IDDiffInterval is the time between two id exports of DeletionIDDiff.
+	// Default is 10 times Interval.
+	IDDiffInterval time.Duration
+	// OnError is called with the errors of a poll, which is retried at the next interval.
+	OnError func(err error)
+}
+
+// Watcher polls the resources sorted by `updatedAt` and `_id`, emitting the
+// resources changed after a high-water mark. Since crud-service sets `updatedAt`,
+// the resources must not be written bypassing it.
+type Watcher[Resource any] struct {
+	client  CrudClient[Resource]
+	options Options
+	watch   WatchOptions
+	changes chan Change[Resource]
+	random  *rand.Rand
+	started atomic.Bool
+
+	mark      watchCheckpoint
+	known     map[string]bool
+	lastDiff  time.Time
+	diffReady bool
+}
+
+type watchCheckpoint struct {
+	UpdatedAt time.Time       `json:"updatedAt"`
+	LastID    json.RawMessage `json:"lastId,omitempty"`
+}
+
+// NewWatcher creates a Watcher of the resources matching the filter of the options.
+// Limit, skip and sort of the filter are replaced.
+func NewWatcher[Resource any](client CrudClient[Resource], options Options, watchOptions WatchOptions) *Watcher[Resource] {
+	if watchOptions.Interval <= 0 {
+		watchOptions.Interval = DefaultWatchInterval
+	}
+	if watchOptions.PageSize <= 0 {
+		watchOptions.PageSize = DefaultPageSize
+	}
+	if watchOptions.IDDiffInterval <= 0 {
+		watchOptions.IDDiffInterval = 10 * watchOptions.Interval
+	}
+	return &Watcher[Resource]{
+		client:  client,
+		options: options,
+		watch:   watchOptions,
+		changes: make(chan Change[Resource]),
+		random:  rand.New(rand.NewSource(time.Now().UnixNano())),
+		known:   map[string]bool{},
+	}
+}
+
+// Changes returns the channel of the changes, which is closed when Run returns.
+func (w *Watcher[Resource]) Changes() <-chan Change[Resource] {
+	return w.changes
+}
+
+// Run polls the changes until ctx is done. The errors of the polls are passed to
+// OnError and the poll is retried, while Run returns the errors loading the checkpoint.
+// The checkpoint is saved after the changes of each page are received from Changes.
+// A Watcher can be run only once.
+func (w *Watcher[Resource]) Run(ctx context.Context) error {
+	if !w.started.CompareAndSwap(false, true) {
+		return fmt.Errorf("%w: watcher already started", ErrWatch)
+	}
+	defer close(w.changes)
+
+	if err := w.loadCheckpoint(ctx); err != nil {
+		return err
+	}
+
+	for {
+		if err := w.poll(ctx); err != nil && ctx.Err() == nil && w.watch.OnError != nil {
+			w.watch.OnError(err)
+		}
+
+		timer := time.NewTimer(w.interval())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (w *Watcher[Resource]) poll(ctx context.Context) error {
+	if w.watch.Deletion == DeletionIDDiff && (!w.diffReady || time.Since(w.lastDiff) >= w.watch.IDDiffInterval) {
+		if err := w.diffIDs(ctx); err != nil {
+			return err
+		}
+	}
+
+	for {
+		resources, err := w.client.List(ctx, w.pageOptions())
+		if err != nil {
+			return err
+		}
+
+		for _, resource := range resources {
+			change, mark, err := w.change(resource)
+			if err != nil {
+				return err
+			}
+			if err := w.emit(ctx, change); err != nil {
+				return err
+			}
+			w.mark = mark
+		}
+		if len(resources) > 0 {
+			if err := w.saveCheckpoint(ctx); err != nil {
+				return err
+			}
+		}
+		if len(resources) < w.watch.PageSize {
+			return nil
+		}
+	}
+}
+
+// readOptions returns the options of the reads of the watcher, which must always
+// reach crud-service to see the latest changes.
+func (w *Watcher[Resource]) readOptions() Options {
+	options := w.options
+	options.SkipDeduplication = true
+	options.SkipCache = true
+	return options
+}
+
+func (w *Watcher[Resource]) pageOptions() Options {
+	options := w.readOptions()
+	options.Filter.Sort = "updatedAt,_id"
+	options.Filter.Limit = w.watch.PageSize
+	options.Filter.Skip = 0
+	if len(options.Filter.Projection) > 0 {
+		options.Filter.Projection = projectionWithID(options.Filter.Projection)
+		for _, field := range []string{"createdAt", "updatedAt", "__STATE__"} {
+			if !contains(options.Filter.Projection, field) {
+				options.Filter.Projection = append(options.Filter.Projection, field)
+			}
+		}
+	}
+
+	if w.watch.Deletion == DeletionTrashState {
+		states := options.Filter.State
+		if len(states) == 0 {
+			states = []State{StatePublic}
+		}
+		options.Filter.State = append([]State{}, states...)
+		for _, state := range []State{StateTrash, StateDeleted} {
+			if !stateSet(options.Filter.State)[state] {
+				options.Filter.State = append(options.Filter.State, state)
+			}
+		}
+	}
+
+	if !w.mark.UpdatedAt.IsZero() {
+		condition := map[string]any{"updatedAt": map[string]any{"$gt": w.mark.UpdatedAt}}
+		if w.mark.LastID != nil {
+			condition = map[string]any{
+				"$or": []any{
+					condition,
+					map[string]any{"updatedAt": w.mark.UpdatedAt, "_id": map[string]any{"$gt": w.mark.LastID}},
+				},
+			}
+		}
+		options.Filter.MongoQuery = andMongoQuery(options.Filter.MongoQuery, condition)
+	}
+	return options
+}
+
+// change returns the change of the resource and the high-water mark after it.
+func (w *Watcher[Resource]) change(resource Resource) (Change[Resource], watchCheckpoint, error) {
+	id, err := resourceID(resource)
+	if err != nil {
+		return Change[Resource]{}, watchCheckpoint{}, err
+	}
+	document, err := normalizeJSON(resource)
+	if err != nil {
+		return Change[Resource]{}, watchCheckpoint{}, err
+	}
+
+	updatedAt, ok := documentTime(document, "updatedAt")
+	if !ok {
+		return Change[Resource]{}, watchCheckpoint{}, fmt.Errorf("%w: resource %s without updatedAt", ErrWatch, id)
+	}
+	createdAt, _ := documentTime(document, "createdAt")
+
+	change := Change[Resource]{Type: ChangeUpdated, ID: resourceIDKey(id), Resource: &resource}
+	if createdAt.Equal(updatedAt) {
+		change.Type = ChangeCreated
+	}
+	if w.watch.Deletion == DeletionTrashState {
+		if states := lookupPath(document, "__STATE__"); len(states) == 1 {
+			if state, _ := states[0].(string); state == string(StateTrash) || state == string(StateDeleted) {
+				change.Type = ChangeDeleted
+			}
+		}
+	}
+	if w.watch.Deletion == DeletionIDDiff && change.Type != ChangeDeleted {
+		w.known[change.ID] = true
+	}
+	return change, watchCheckpoint{UpdatedAt: updatedAt, LastID: id}, nil
+}
+
+// diffIDs exports the ids of the resources, emitting as deleted the known ids that
+// are not exported. The first export only sets the known ids.
+func (w *Watcher[Resource]) diffIDs(ctx context.Context) error {
+	options := w.readOptions()
+	options.Filter.Projection = []string{"_id"}
+	options.Filter.Limit = 0
+	options.Filter.Skip = 0
+	options.Filter.Sort = ""
+
+	current := map[string]bool{}
+	if _, err := w.client.ExportStream(ctx, options, func(resource Resource) error {
+		id, err := resourceID(resource)
+		if err != nil {
+			return err
+		}
+		current[resourceIDKey(id)] = true
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if w.diffReady {
+		for _, id := range sortedKeys(w.known) {
+			if current[id] {
+				continue
+			}
+			if err := w.emit(ctx, Change[Resource]{Type: ChangeDeleted, ID: id}); err != nil {
+				return err
+			}
+		}
+	}
+	w.known = current
+	w.lastDiff = time.Now()
+	w.diffReady = true
+	return nil
+}
+
+func (w *Watcher[Resource]) emit(ctx context.Context, change Change[Resource]) error {
+	select {
+	case w.changes <- change:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *Watcher[Resource]) interval() time.Duration {
+	if w.watch.Jitter <= 0 {
+		return w.watch.Interval
+	}
+	jitter := (w.random.Float64()*2 - 1) * w.watch.Jitter
+	return time.Duration(float64(w.watch.Interval) * (1 + jitter))
+}
+
+func (w *Watcher[Resource]) loadCheckpoint(ctx context.Context) error {
+	w.mark = watchCheckpoint{UpdatedAt: w.watch.Since}
+	if w.watch.Store == nil {
+		return nil
+	}
+	data, err := w.watch.Store.Load(ctx)
+	if err != nil || data == nil {
+		return err
+	}
+	checkpoint := watchCheckpoint{}
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return fmt.Errorf("%w: %s", ErrCheckpoint, err)
+	}
+	w.mark = checkpoint
+	return nil
+}
+
+func (w *Watcher[Resource]) saveCheckpoint(ctx context.Context) error {
+	if w.watch.Store == nil {
+		return nil
+	}
+	data, err := json.Marshal(w.mark)
+	if err != nil {
+		return err
+	}
+	return w.watch.Store.Save(ctx, data)
+}
+
+func documentTime(document any, field string) (time.Time, bool) {
+	values := lookupPath(document, field)
+	if len(values) != 1 {
+		return time.Time{}, false
+	}
+	value, ok := values[0].(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
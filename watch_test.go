@@ -0,0 +1,329 @@
+// Copyright Mia srl
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type watchResource struct {
+	ID        string `json:"_id"`
+	CreatedAt string `json:"createdAt,omitempty"`
+	UpdatedAt string `json:"updatedAt,omitempty"`
+	State     string `json:"__STATE__,omitempty"`
+}
+
+type watchClient struct {
+	CrudClient[watchResource]
+
+	mu       sync.Mutex
+	pages    [][]watchResource
+	exports  [][]watchResource
+	errs     []error
+	lists    []Options
+	exported []Options
+}
+
+func (c *watchClient) List(_ context.Context, options Options) ([]watchResource, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lists = append(c.lists, options)
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		return nil, err
+	}
+	if len(c.pages) == 0 {
+		return []watchResource{}, nil
+	}
+	page := c.pages[0]
+	c.pages = c.pages[1:]
+	return page, nil
+}
+
+func (c *watchClient) ExportStream(_ context.Context, options Options, fn func(resource watchResource) error) (ExportProgress, error) {
+	c.mu.Lock()
+	c.exported = append(c.exported, options)
+	var resources []watchResource
+	if len(c.exports) > 0 {
+		resources = c.exports[0]
+		c.exports = c.exports[1:]
+	}
+	c.mu.Unlock()
+
+	for _, resource := range resources {
+		if err := fn(resource); err != nil {
+			return ExportProgress{}, err
+		}
+	}
+	return ExportProgress{Documents: len(resources)}, nil
+}
+
+func (c *watchClient) listed() []Options {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Options{}, c.lists...)
+}
+
+func runWatcher(t *testing.T, watcher *Watcher[watchResource], changes int) []Change[watchResource] {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- watcher.Run(ctx)
+	}()
+
+	received := []Change[watchResource]{}
+	timeout := time.After(5 * time.Second)
+	for len(received) < changes {
+		select {
+		case change := <-watcher.Changes():
+			received = append(received, change)
+		case <-timeout:
+			t.Fatalf("received %d changes of %d", len(received), changes)
+		}
+	}
+	cancel()
+
+	require.ErrorIs(t, <-done, context.Canceled)
+	_, open := <-watcher.Changes()
+	require.False(t, open)
+	return received
+}
+
+func TestWatcher(t *testing.T) {
+	t1 := "2024-01-01T00:00:00.000Z"
+	t2 := "2024-01-01T00:00:01.000Z"
+	t3 := "2024-01-01T00:00:02.000Z"
+
+	t.Run("emit created and updated resources", func(t *testing.T) {
+		client := &watchClient{
+			pages: [][]watchResource{
+				{
+					{ID: "1", CreatedAt: t1, UpdatedAt: t1},
+					{ID: "2", CreatedAt: t1, UpdatedAt: t2},
+				},
+				{},
+				{{ID: "3", CreatedAt: t3, UpdatedAt: t3}},
+			},
+		}
+		store := &memoryCheckpointStore{}
+		watcher := NewWatcher[watchResource](client, Options{
+			Filter: Filter{MongoQuery: map[string]any{"field": "v"}, Limit: 5, Skip: 3},
+		}, WatchOptions{
+			Interval: time.Millisecond,
+			PageSize: 2,
+			Store:    store,
+		})
+
+		changes := runWatcher(t, watcher, 3)
+		require.Equal(t, []ChangeType{ChangeCreated, ChangeUpdated, ChangeCreated}, []ChangeType{changes[0].Type, changes[1].Type, changes[2].Type})
+		require.Equal(t, []string{"1", "2", "3"}, []string{changes[0].ID, changes[1].ID, changes[2].ID})
+		require.Equal(t, &watchResource{ID: "2", CreatedAt: t1, UpdatedAt: t2}, changes[1].Resource)
+
+		lists := client.listed()
+		require.Equal(t, "updatedAt,_id", lists[0].Filter.Sort)
+		require.Equal(t, 2, lists[0].Filter.Limit)
+		require.Zero(t, lists[0].Filter.Skip)
+		require.Equal(t, map[string]any{"field": "v"}, lists[0].Filter.MongoQuery)
+
+		query, err := json.Marshal(encodeQueryValues(lists[1].Filter.MongoQuery))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"$and":[{"field":"v"},{"$or":[
+			{"updatedAt":{"$gt":"2024-01-01T00:00:01.000Z"}},
+			{"updatedAt":"2024-01-01T00:00:01.000Z","_id":{"$gt":"2"}}
+		]}]}`, string(query))
+		require.JSONEq(t, `{"updatedAt":"2024-01-01T00:00:02Z","lastId":"3"}`, string(store.checkpoint))
+	})
+
+	t.Run("resume from the checkpoint", func(t *testing.T) {
+		client := &watchClient{}
+		store := &memoryCheckpointStore{checkpoint: []byte(`{"updatedAt":"2024-01-01T00:00:01Z","lastId":"2"}`)}
+		watcher := NewWatcher[watchResource](client, Options{}, WatchOptions{Interval: time.Millisecond, Store: store})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- watcher.Run(ctx) }()
+		require.Eventually(t, func() bool { return len(client.listed()) > 0 }, time.Second, time.Millisecond)
+		cancel()
+		<-done
+
+		query := client.listed()[0].Filter.MongoQuery
+		require.Contains(t, query, "$or")
+	})
+
+	t.Run("start since a time", func(t *testing.T) {
+		client := &watchClient{}
+		since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		watcher := NewWatcher[watchResource](client, Options{}, WatchOptions{Interval: time.Millisecond, Since: since})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- watcher.Run(ctx) }()
+		require.Eventually(t, func() bool { return len(client.listed()) > 0 }, time.Second, time.Millisecond)
+		cancel()
+		<-done
+
+		require.Equal(t, map[string]any{"updatedAt": map[string]any{"$gt": since}}, client.listed()[0].Filter.MongoQuery)
+	})
+
+	t.Run("detect deletions by trash state", func(t *testing.T) {
+		client := &watchClient{
+			pages: [][]watchResource{
+				{
+					{ID: "1", CreatedAt: t1, UpdatedAt: t2, State: "TRASH"},
+					{ID: "2", CreatedAt: t1, UpdatedAt: t2, State: "PUBLIC"},
+				},
+			},
+		}
+		watcher := NewWatcher[watchResource](client, Options{
+			Filter: Filter{Projection: []string{"field"}},
+		}, WatchOptions{
+			Interval: time.Millisecond,
+			Deletion: DeletionTrashState,
+		})
+
+		changes := runWatcher(t, watcher, 2)
+		require.Equal(t, ChangeDeleted, changes[0].Type)
+		require.Equal(t, ChangeUpdated, changes[1].Type)
+
+		list := client.listed()[0]
+		require.Equal(t, []State{StatePublic, StateTrash, StateDeleted}, list.Filter.State)
+		require.Equal(t, []string{"field", "_id", "createdAt", "updatedAt", "__STATE__"}, list.Filter.Projection)
+	})
+
+	t.Run("detect deletions by id diff", func(t *testing.T) {
+		client := &watchClient{
+			exports: [][]watchResource{
+				{{ID: "1"}, {ID: "2"}, {ID: "3"}},
+				{{ID: "2"}},
+			},
+			pages: [][]watchResource{
+				{{ID: "4", CreatedAt: t3, UpdatedAt: t3}},
+			},
+		}
+		watcher := NewWatcher[watchResource](client, Options{}, WatchOptions{
+			Interval:       time.Millisecond,
+			Deletion:       DeletionIDDiff,
+			IDDiffInterval: time.Millisecond,
+		})
+
+		changes := runWatcher(t, watcher, 4)
+		require.Equal(t, Change[watchResource]{Type: ChangeCreated, ID: "4", Resource: &watchResource{ID: "4", CreatedAt: t3, UpdatedAt: t3}}, changes[0])
+		require.Equal(t, []Change[watchResource]{
+			{Type: ChangeDeleted, ID: "1"},
+			{Type: ChangeDeleted, ID: "3"},
+			{Type: ChangeDeleted, ID: "4"},
+		}, changes[1:])
+
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		for _, options := range client.exported {
+			require.True(t, options.SkipDeduplication)
+			require.True(t, options.SkipCache)
+		}
+	})
+
+	t.Run("skip deduplication and cache of the lists", func(t *testing.T) {
+		client := &watchClient{pages: [][]watchResource{{{ID: "1", CreatedAt: t1, UpdatedAt: t1}}}}
+		watcher := NewWatcher[watchResource](client, Options{}, WatchOptions{Interval: time.Millisecond})
+
+		runWatcher(t, watcher, 1)
+		for _, options := range client.listed() {
+			require.True(t, options.SkipDeduplication)
+			require.True(t, options.SkipCache)
+		}
+	})
+
+	t.Run("run only once", func(t *testing.T) {
+		watcher := NewWatcher[watchResource](&watchClient{}, Options{}, WatchOptions{Interval: time.Millisecond})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.ErrorIs(t, watcher.Run(ctx), context.Canceled)
+
+		err := watcher.Run(context.Background())
+		require.ErrorIs(t, err, ErrWatch)
+		require.EqualError(t, err, "watch error: watcher already started")
+	})
+
+	t.Run("report errors and retry", func(t *testing.T) {
+		expectedErr := errors.New("crud error")
+		client := &watchClient{
+			errs:  []error{expectedErr},
+			pages: [][]watchResource{{{ID: "1", CreatedAt: t1, UpdatedAt: t1}}},
+		}
+
+		var mu sync.Mutex
+		reported := []error{}
+		watcher := NewWatcher[watchResource](client, Options{}, WatchOptions{
+			Interval: time.Millisecond,
+			OnError: func(err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				reported = append(reported, err)
+			},
+		})
+
+		changes := runWatcher(t, watcher, 1)
+		require.Equal(t, "1", changes[0].ID)
+		mu.Lock()
+		defer mu.Unlock()
+		require.Equal(t, []error{expectedErr}, reported)
+	})
+
+	t.Run("report resources without updatedAt", func(t *testing.T) {
+		client := &watchClient{pages: [][]watchResource{{{ID: "1"}}}}
+		reported := make(chan error, 1)
+		watcher := NewWatcher[watchResource](client, Options{}, WatchOptions{
+			Interval: time.Hour,
+			OnError:  func(err error) { reported <- err },
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go watcher.Run(ctx)
+		require.ErrorIs(t, <-reported, ErrWatch)
+	})
+
+	t.Run("throws with invalid checkpoint", func(t *testing.T) {
+		watcher := NewWatcher[watchResource](&watchClient{}, Options{}, WatchOptions{
+			Store: &memoryCheckpointStore{checkpoint: []byte(`{`)},
+		})
+		require.ErrorIs(t, watcher.Run(context.Background()), ErrCheckpoint)
+	})
+}
+
+func TestWatcherInterval(t *testing.T) {
+	watcher := NewWatcher[watchResource](&watchClient{}, Options{}, WatchOptions{Interval: time.Second, Jitter: 0.2})
+	for i := 0; i < 100; i++ {
+		interval := watcher.interval()
+		require.GreaterOrEqual(t, interval, 800*time.Millisecond)
+		require.LessOrEqual(t, interval, 1200*time.Millisecond)
+	}
+
+	watcher = NewWatcher[watchResource](&watchClient{}, Options{}, WatchOptions{})
+	require.Equal(t, DefaultWatchInterval, watcher.interval())
+}